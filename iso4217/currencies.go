@@ -0,0 +1,65 @@
+package iso4217
+
+// Default is the registry of currencies the service knows about
+var Default = mustRegistry(currencies)
+
+// currencies lists the supported ISO 4217 currencies. Withdrawn codes
+// are kept as inactive so clients get a precise error for them.
+var currencies = []Currency{
+	{Code: "AED", Numeric: "784", Name: "UAE Dirham", MinorUnits: 2, Active: true},
+	{Code: "ARS", Numeric: "032", Name: "Argentine Peso", MinorUnits: 2, Active: true},
+	{Code: "AUD", Numeric: "036", Name: "Australian Dollar", MinorUnits: 2, Active: true},
+	{Code: "BGN", Numeric: "975", Name: "Bulgarian Lev", MinorUnits: 2, Active: true},
+	{Code: "BHD", Numeric: "048", Name: "Bahraini Dinar", MinorUnits: 3, Active: true},
+	{Code: "BRL", Numeric: "986", Name: "Brazilian Real", MinorUnits: 2, Active: true},
+	{Code: "CAD", Numeric: "124", Name: "Canadian Dollar", MinorUnits: 2, Active: true},
+	{Code: "CHF", Numeric: "756", Name: "Swiss Franc", MinorUnits: 2, Active: true},
+	{Code: "CLP", Numeric: "152", Name: "Chilean Peso", MinorUnits: 0, Active: true},
+	{Code: "CNY", Numeric: "156", Name: "Yuan Renminbi", MinorUnits: 2, Active: true},
+	{Code: "CZK", Numeric: "203", Name: "Czech Koruna", MinorUnits: 2, Active: true},
+	{Code: "DKK", Numeric: "208", Name: "Danish Krone", MinorUnits: 2, Active: true},
+	{Code: "EUR", Numeric: "978", Name: "Euro", MinorUnits: 2, Active: true},
+	{Code: "GBP", Numeric: "826", Name: "Pound Sterling", MinorUnits: 2, Active: true},
+	{Code: "HKD", Numeric: "344", Name: "Hong Kong Dollar", MinorUnits: 2, Active: true},
+	{Code: "HUF", Numeric: "348", Name: "Forint", MinorUnits: 2, Active: true},
+	{Code: "IDR", Numeric: "360", Name: "Rupiah", MinorUnits: 2, Active: true},
+	{Code: "ILS", Numeric: "376", Name: "New Israeli Sheqel", MinorUnits: 2, Active: true},
+	{Code: "INR", Numeric: "356", Name: "Indian Rupee", MinorUnits: 2, Active: true},
+	{Code: "ISK", Numeric: "352", Name: "Iceland Krona", MinorUnits: 0, Active: true},
+	{Code: "JOD", Numeric: "400", Name: "Jordanian Dinar", MinorUnits: 3, Active: true},
+	{Code: "JPY", Numeric: "392", Name: "Yen", MinorUnits: 0, Active: true},
+	{Code: "KRW", Numeric: "410", Name: "Won", MinorUnits: 0, Active: true},
+	{Code: "KWD", Numeric: "414", Name: "Kuwaiti Dinar", MinorUnits: 3, Active: true},
+	{Code: "MXN", Numeric: "484", Name: "Mexican Peso", MinorUnits: 2, Active: true},
+	{Code: "MYR", Numeric: "458", Name: "Malaysian Ringgit", MinorUnits: 2, Active: true},
+	{Code: "NOK", Numeric: "578", Name: "Norwegian Krone", MinorUnits: 2, Active: true},
+	{Code: "NZD", Numeric: "554", Name: "New Zealand Dollar", MinorUnits: 2, Active: true},
+	{Code: "OMR", Numeric: "512", Name: "Rial Omani", MinorUnits: 3, Active: true},
+	{Code: "PHP", Numeric: "608", Name: "Philippine Peso", MinorUnits: 2, Active: true},
+	{Code: "PLN", Numeric: "985", Name: "Zloty", MinorUnits: 2, Active: true},
+	{Code: "RON", Numeric: "946", Name: "Romanian Leu", MinorUnits: 2, Active: true},
+	{Code: "SEK", Numeric: "752", Name: "Swedish Krona", MinorUnits: 2, Active: true},
+	{Code: "SGD", Numeric: "702", Name: "Singapore Dollar", MinorUnits: 2, Active: true},
+	{Code: "THB", Numeric: "764", Name: "Baht", MinorUnits: 2, Active: true},
+	{Code: "TND", Numeric: "788", Name: "Tunisian Dinar", MinorUnits: 3, Active: true},
+	{Code: "TRY", Numeric: "949", Name: "Turkish Lira", MinorUnits: 2, Active: true},
+	{Code: "UAH", Numeric: "980", Name: "Hryvnia", MinorUnits: 2, Active: true},
+	{Code: "USD", Numeric: "840", Name: "US Dollar", MinorUnits: 2, Active: true},
+	{Code: "VND", Numeric: "704", Name: "Dong", MinorUnits: 0, Active: true},
+	{Code: "ZAR", Numeric: "710", Name: "Rand", MinorUnits: 2, Active: true},
+
+	// Withdrawn currencies
+	{Code: "DEM", Numeric: "276", Name: "Deutsche Mark", MinorUnits: 2, Active: false},
+	{Code: "FRF", Numeric: "250", Name: "French Franc", MinorUnits: 2, Active: false},
+	{Code: "HRK", Numeric: "191", Name: "Kuna", MinorUnits: 2, Active: false},
+	{Code: "ITL", Numeric: "380", Name: "Italian Lira", MinorUnits: 0, Active: false},
+}
+
+// mustRegistry builds a registry from built-in data and panics if it is invalid
+func mustRegistry(list []Currency) *Registry {
+	r, err := NewRegistry(list)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
@@ -0,0 +1,3 @@
+module iso4217
+
+go 1.21
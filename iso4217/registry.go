@@ -0,0 +1,143 @@
+// Package iso4217 is a registry of ISO 4217 currencies with the metadata
+// needed to validate and format amounts.
+package iso4217
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Currency describes an ISO 4217 currency
+type Currency struct {
+	// Code is the alphabetic code, e.g. "USD"
+	Code string `json:"code"`
+	// Numeric is the three-digit numeric code, e.g. "840"
+	Numeric string `json:"numeric"`
+	// Name is the official currency name
+	Name string `json:"name"`
+	// MinorUnits is the number of fraction digits, e.g. 2 for USD and 0 for JPY
+	MinorUnits int `json:"minorUnits"`
+	// Active reports whether the currency can be used in conversions
+	Active bool `json:"active"`
+}
+
+// UnknownCurrencyError is returned for codes that are not in the registry
+type UnknownCurrencyError struct {
+	Code string
+}
+
+func (e *UnknownCurrencyError) Error() string {
+	return fmt.Sprintf("%q is not a known ISO 4217 currency code", e.Code)
+}
+
+// InactiveCurrencyError is returned for registered currencies that are not active
+type InactiveCurrencyError struct {
+	Currency Currency
+}
+
+func (e *InactiveCurrencyError) Error() string {
+	return fmt.Sprintf("currency %s (%s) is not active", e.Currency.Code, e.Currency.Name)
+}
+
+// Registry holds the known currencies. It is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	byCode map[string]Currency
+}
+
+// NewRegistry creates a registry from the given currencies
+func NewRegistry(currencies []Currency) (*Registry, error) {
+	r := &Registry{byCode: make(map[string]Currency, len(currencies))}
+	for _, c := range currencies {
+		if err := validate(c); err != nil {
+			return nil, err
+		}
+		if _, dup := r.byCode[c.Code]; dup {
+			return nil, fmt.Errorf("duplicate currency code %s", c.Code)
+		}
+		r.byCode[c.Code] = c
+	}
+	return r, nil
+}
+
+// Lookup returns a registered currency, active or not
+func (r *Registry) Lookup(code string) (Currency, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.byCode[code]
+	if !ok {
+		return Currency{}, &UnknownCurrencyError{Code: code}
+	}
+	return c, nil
+}
+
+// Validate returns the currency for code if it is registered and active
+func (r *Registry) Validate(code string) (Currency, error) {
+	c, err := r.Lookup(code)
+	if err != nil {
+		return Currency{}, err
+	}
+	if !c.Active {
+		return Currency{}, &InactiveCurrencyError{Currency: c}
+	}
+	return c, nil
+}
+
+// SetActive enables or disables a registered currency
+func (r *Registry) SetActive(code string, active bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.byCode[code]
+	if !ok {
+		return &UnknownCurrencyError{Code: code}
+	}
+	c.Active = active
+	r.byCode[code] = c
+	return nil
+}
+
+// List returns the registered currencies sorted by code.
+// Inactive currencies are only included if includeInactive is set.
+func (r *Registry) List(includeInactive bool) []Currency {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Currency, 0, len(r.byCode))
+	for _, c := range r.byCode {
+		if c.Active || includeInactive {
+			list = append(list, c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// validate checks the format of a currency definition
+func validate(c Currency) error {
+	if !isCode(c.Code, 'A', 'Z') {
+		return fmt.Errorf("invalid currency code %q", c.Code)
+	}
+	if !isCode(c.Numeric, '0', '9') {
+		return fmt.Errorf("invalid numeric code %q for %s", c.Numeric, c.Code)
+	}
+	if c.MinorUnits < 0 || c.MinorUnits > 4 {
+		return fmt.Errorf("invalid minor units %d for %s", c.MinorUnits, c.Code)
+	}
+	return nil
+}
+
+// isCode reports whether s is three characters in the range lo..hi
+func isCode(s string, lo, hi byte) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < lo || s[i] > hi {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+// Package money provides an exact decimal type for monetary amounts and
+// exchange rates, with rounding to ISO 4217 minor units.
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// maxAutoPlaces caps the digits printed for values that have no finite
+// decimal representation, such as rates derived by division
+const maxAutoPlaces = 18
+
+// Decimal is an exact decimal number. The zero value is 0.
+//
+// Values are immutable: every operation returns a new Decimal.
+type Decimal struct {
+	r *big.Rat
+	// places is the number of fraction digits to print, or -1 to print
+	// the shortest exact representation
+	places int
+}
+
+// Zero is the decimal 0
+var Zero = Decimal{}
+
+// NewFromInt creates a decimal from an integer
+func NewFromInt(i int64) Decimal {
+	return Decimal{r: new(big.Rat).SetInt64(i), places: 0}
+}
+
+// NewFromFloat creates a decimal from the shortest decimal representation of f
+func NewFromFloat(f float64) Decimal {
+	r, ok := new(big.Rat).SetString(fmt.Sprintf("%v", f))
+	if !ok {
+		return Zero
+	}
+	return Decimal{r: r, places: -1}
+}
+
+// Parse reads a decimal in plain notation, e.g. "-12.50".
+// Exponents, fractions and other forms accepted by big.Rat are rejected.
+func Parse(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 || digits == "" {
+		return Zero, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	intPart, fracPart, hasPoint := strings.Cut(digits, ".")
+	if intPart == "" && fracPart == "" {
+		return Zero, fmt.Errorf("invalid decimal %q", s)
+	}
+	for _, part := range []string{intPart, fracPart} {
+		for _, ch := range part {
+			if ch < '0' || ch > '9' {
+				return Zero, fmt.Errorf("invalid decimal %q", s)
+			}
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Zero, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	places := 0
+	if hasPoint {
+		places = len(fracPart)
+	}
+	return Decimal{r: r, places: places}, nil
+}
+
+// MustParse is like Parse but panics on invalid input.
+// It is meant for constants in code.
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// rat returns the underlying rational, treating the zero value as 0
+func (d Decimal) rat() *big.Rat {
+	if d.r == nil {
+		return new(big.Rat)
+	}
+	return d.r
+}
+
+// Add returns d + o
+func (d Decimal) Add(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Add(d.rat(), o.rat()), places: maxPlaces(d.places, o.places)}
+}
+
+// Sub returns d - o
+func (d Decimal) Sub(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Sub(d.rat(), o.rat()), places: maxPlaces(d.places, o.places)}
+}
+
+// Mul returns d * o
+func (d Decimal) Mul(o Decimal) Decimal {
+	places := -1
+	if d.places >= 0 && o.places >= 0 {
+		places = d.places + o.places
+	}
+	return Decimal{r: new(big.Rat).Mul(d.rat(), o.rat()), places: places}
+}
+
+// Div returns d / o. The result is exact; use Round to bring it to a
+// fixed number of places. Division by zero returns an error.
+func (d Decimal) Div(o Decimal) (Decimal, error) {
+	if o.Sign() == 0 {
+		return Zero, fmt.Errorf("division by zero")
+	}
+	return Decimal{r: new(big.Rat).Quo(d.rat(), o.rat()), places: -1}, nil
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{r: new(big.Rat).Neg(d.rat()), places: d.places}
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d
+func (d Decimal) Sign() int {
+	return d.rat().Sign()
+}
+
+// IsZero reports whether d is 0
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and o and returns -1, 0 or +1
+func (d Decimal) Cmp(o Decimal) int {
+	return d.rat().Cmp(o.rat())
+}
+
+// Round rounds d to the given number of fraction digits using mode.
+// The result prints with exactly that many fraction digits.
+func (d Decimal) Round(places int, mode RoundingMode) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+
+	scaled := new(big.Rat).Mul(d.rat(), new(big.Rat).SetInt(scale))
+	quo, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if rem.Sign() != 0 && mode.roundAway(quo, rem, scaled.Denom(), scaled.Sign()) {
+		if scaled.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+
+	return Decimal{r: new(big.Rat).SetFrac(quo, scale), places: places}
+}
+
+// Normalize drops any fixed number of places so d prints in its shortest form
+func (d Decimal) Normalize() Decimal {
+	return Decimal{r: d.r, places: -1}
+}
+
+// Float64 returns the nearest float64 value of d
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+// String prints d in plain decimal notation
+func (d Decimal) String() string {
+	r := d.rat()
+	if d.places >= 0 {
+		return r.FloatString(d.places)
+	}
+
+	if places, ok := exactPlaces(r); ok {
+		return r.FloatString(places)
+	}
+	s := r.FloatString(maxAutoPlaces)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// MarshalText implements encoding.TextMarshaler, used for XML and YAML
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, used for XML and YAML
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// MarshalJSON encodes d as a JSON number
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding a decimal
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		s = string(data)
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// exactPlaces returns the number of fraction digits needed to print r
+// exactly, or false if r has no finite decimal representation
+func exactPlaces(r *big.Rat) (int, bool) {
+	den := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	two, five := big.NewInt(2), big.NewInt(5)
+	mod := new(big.Int)
+	for {
+		q, m := new(big.Int).QuoRem(den, two, mod)
+		if m.Sign() != 0 {
+			break
+		}
+		den, twos = q, twos+1
+	}
+	for {
+		q, m := new(big.Int).QuoRem(den, five, mod)
+		if m.Sign() != 0 {
+			break
+		}
+		den, fives = q, fives+1
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	return max(twos, fives), true
+}
+
+// maxPlaces combines the print places of two operands
+func maxPlaces(a, b int) int {
+	if a < 0 || b < 0 {
+		return -1
+	}
+	return max(a, b)
+}
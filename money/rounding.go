@@ -0,0 +1,91 @@
+package money
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RoundingMode decides how a value is rounded to a fixed number of places
+type RoundingMode int
+
+const (
+	// HalfUp rounds to nearest, ties away from zero
+	HalfUp RoundingMode = iota
+	// HalfEven rounds to nearest, ties to the even neighbour (banker's rounding)
+	HalfEven
+	// HalfDown rounds to nearest, ties toward zero
+	HalfDown
+	// Up rounds away from zero
+	Up
+	// Down rounds toward zero (truncation)
+	Down
+	// Ceiling rounds toward positive infinity
+	Ceiling
+	// Floor rounds toward negative infinity
+	Floor
+)
+
+var roundingModeNames = map[RoundingMode]string{
+	HalfUp:   "half-up",
+	HalfEven: "half-even",
+	HalfDown: "half-down",
+	Up:       "up",
+	Down:     "down",
+	Ceiling:  "ceiling",
+	Floor:    "floor",
+}
+
+// String returns the configuration name of the mode
+func (m RoundingMode) String() string {
+	if name, ok := roundingModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+// ParseRoundingMode reads a mode by its configuration name, e.g. "half-even"
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for mode, name := range roundingModeNames {
+		if name == s {
+			return mode, nil
+		}
+	}
+	return HalfUp, fmt.Errorf("unknown rounding mode %q", s)
+}
+
+// roundAway reports whether a truncated quotient must move one step away
+// from zero. rem is the non-zero truncation remainder of a division by den
+// and sign is the sign of the exact value.
+func (m RoundingMode) roundAway(quo, rem, den *big.Int, sign int) bool {
+	switch m {
+	case Up:
+		return true
+	case Down:
+		return false
+	case Ceiling:
+		return sign > 0
+	case Floor:
+		return sign < 0
+	}
+
+	// Compare the discarded fraction with one half
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	switch twice.Cmp(den) {
+	case 1:
+		return true
+	case -1:
+		return false
+	}
+
+	switch m {
+	case HalfEven:
+		return quo.Bit(0) == 1
+	case HalfDown:
+		return false
+	default:
+		return true
+	}
+}
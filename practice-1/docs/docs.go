@@ -15,6 +15,89 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/admin/rates": {
+            "get": {
+                "description": "Returns the exchange rates currently used by the SOAP service",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List exchange rates",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/soap.RateTable"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Adds or replaces a currency's rate against the base currency without restarting the service",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Update an exchange rate",
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "parameters": [
+                    {
+                        "description": "Rate to set",
+                        "name": "rate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RateUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.RateUpdate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/health": {
             "get": {
                 "description": "Returns the health status of the API",
@@ -36,6 +119,62 @@ const docTemplate = `{
                 }
             }
         }
+    },
+    "definitions": {
+        "main.RateUpdate": {
+            "type": "object",
+            "required": [
+                "currency",
+                "rate"
+            ],
+            "properties": {
+                "currency": {
+                    "$ref": "#/definitions/soap.Currency"
+                },
+                "rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "soap.Currency": {
+            "type": "string",
+            "enum": [
+                "USD",
+                "UAH",
+                "EUR",
+                "GBP",
+                "JPY"
+            ],
+            "x-enum-varnames": [
+                "USD",
+                "UAH",
+                "EUR",
+                "GBP",
+                "JPY"
+            ]
+        },
+        "soap.RateTable": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "$ref": "#/definitions/soap.Currency"
+                },
+                "rates": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "\"Bearer \" followed by the ADMIN_TOKEN of the service",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -4,10 +4,21 @@ go 1.21
 
 require (
 	github.com/gin-gonic/gin v1.9.1
+	github.com/go-playground/validator/v10 v10.14.0
 	github.com/joho/godotenv v1.5.1
 	github.com/swaggo/files v1.0.1
 	github.com/swaggo/gin-swagger v1.6.0
 	github.com/swaggo/swag v1.16.3
+	golang.org/x/net v0.17.0
+	gopkg.in/yaml.v3 v3.0.1
+	iso4217 v0.0.0
+	money v0.0.0
+	pricing v0.0.0
+	rates v0.0.0
+	soapfault v0.0.0
+	soapheader v0.0.0
+	wssecurity v0.0.0
+	xmllimit v0.0.0
 )
 
 require (
@@ -24,7 +35,6 @@ require (
 	github.com/go-openapi/swag v0.19.15 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
-	github.com/go-playground/validator/v10 v10.14.0 // indirect
 	github.com/goccy/go-json v0.10.2 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
@@ -39,11 +49,25 @@ require (
 	github.com/ugorji/go/codec v1.2.11 // indirect
 	golang.org/x/arch v0.3.0 // indirect
 	golang.org/x/crypto v0.14.0 // indirect
-	golang.org/x/net v0.17.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
 	golang.org/x/tools v0.7.0 // indirect
 	google.golang.org/protobuf v1.30.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace iso4217 => ../iso4217
+
+replace money => ../money
+
+replace pricing => ../pricing
+
+replace rates => ../rates
+
+replace soapfault => ../soapfault
+
+replace soapheader => ../soapheader
+
+replace wssecurity => ../wssecurity
+
+replace xmllimit => ../xmllimit
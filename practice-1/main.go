@@ -1,12 +1,27 @@
 package main
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"iso4217"
 	"log"
+	"money"
+	"net/http"
 	"os"
 	_ "practice-1/docs" // This is where the generated swagger docs will be
 	"practice-1/soap"
+	"practice-1/wsdl"
+	"pricing"
+	"reflect"
+	"strconv"
+	"strings"
+	"wssecurity"
+	"xmllimit"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -18,11 +33,27 @@ import (
 // @host           localhost:8080
 // @BasePath       /api/v1
 
+// @securityDefinitions.apikey  AdminToken
+// @in                          header
+// @name                        Authorization
+// @description                 "Bearer " followed by the ADMIN_TOKEN of the service
+
 func init() {
 	// Load .env file if it exists
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
 	}
+
+	// Name fields by their JSON names in binding errors
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
 }
 
 // @Summary      Health check endpoint
@@ -37,17 +68,211 @@ func healthCheck(c *gin.Context) {
 	})
 }
 
-func initializeRoutes(router *gin.Engine) {
+// RateUpdate is the payload for updating a currency's rate against the base
+// currency. Rate is a pointer so that binding can tell a missing rate from a
+// zero one.
+type RateUpdate struct {
+	Currency soap.Currency  `json:"currency" binding:"required"`
+	Rate     *money.Decimal `json:"rate" binding:"required" swaggertype:"number"`
+}
+
+// @Summary      List exchange rates
+// @Description  Returns the exchange rates currently used by the SOAP service
+// @Tags         admin
+// @Produce      json
+// @Security     AdminToken
+// @Success      200  {object}  soap.RateTable
+// @Failure      401  {object}  map[string]interface{}
+// @Router       /api/v1/admin/rates [get]
+func listRates(rates *soap.MemoryRateProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, rates.Snapshot())
+	}
+}
+
+// @Summary      Update an exchange rate
+// @Description  Adds or replaces a currency's rate against the base currency without restarting the service
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     AdminToken
+// @Param        rate  body      RateUpdate  true  "Rate to set"
+// @Success      200   {object}  RateUpdate
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      401   {object}  map[string]interface{}
+// @Router       /api/v1/admin/rates [put]
+func updateRate(rates *soap.MemoryRateProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var update RateUpdate
+		if err := c.ShouldBindJSON(&update); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bindingError(err)})
+			return
+		}
+
+		if _, err := iso4217.Default.Validate(string(update.Currency)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := rates.SetRate(update.Currency, *update.Rate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, update)
+	}
+}
+
+// bindingError describes a payload that failed to bind, naming the fields
+// that are missing by their JSON names
+func bindingError(err error) string {
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err.Error()
+	}
+	messages := make([]string, len(invalid))
+	for i, field := range invalid {
+		if field.Tag() == "required" {
+			messages[i] = field.Field() + " is required"
+		} else {
+			messages[i] = fmt.Sprintf("%s fails the %s check", field.Field(), field.Tag())
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// requireAdminToken rejects requests without an "Authorization: Bearer"
+// header carrying the admin token
+func requireAdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or missing admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// newRateProvider builds the rate store, seeding the current rates from
+// RATES_FILE and past rate snapshots from RATES_HISTORY_FILE if set
+func newRateProvider() (*soap.MemoryRateProvider, error) {
+	table := soap.DefaultRates
+	if path := os.Getenv("RATES_FILE"); path != "" {
+		var err error
+		if table, err = soap.LoadRateFile(path); err != nil {
+			return nil, err
+		}
+	}
+	rates, err := soap.NewMemoryRateProvider(table)
+	if err != nil {
+		return nil, err
+	}
+
+	if path := os.Getenv("RATES_HISTORY_FILE"); path != "" {
+		snapshots, err := soap.LoadRateHistoryFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range snapshots {
+			if err := rates.AddSnapshot(snapshot); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rates, nil
+}
+
+// roundingMode reads the rounding mode for converted amounts from ROUNDING_MODE
+func roundingMode() (money.RoundingMode, error) {
+	name := os.Getenv("ROUNDING_MODE")
+	if name == "" {
+		return money.HalfUp, nil
+	}
+	return money.ParseRoundingMode(name)
+}
+
+// pricingSchedule loads spreads and fees from PRICING_FILE; without one,
+// conversions are priced at the mid rate without fees
+func pricingSchedule() (*pricing.Schedule, error) {
+	path := os.Getenv("PRICING_FILE")
+	if path == "" {
+		return &pricing.Schedule{}, nil
+	}
+	return pricing.LoadFile(path)
+}
+
+// securityVerifier authenticates SOAP requests against the users of
+// WSS_CREDENTIALS_FILE; without one, requests are not authenticated.
+// WSS_REQUIRE_TIMESTAMP also requires a wsu:Timestamp in every request.
+func securityVerifier() (*wssecurity.Verifier, error) {
+	path := os.Getenv("WSS_CREDENTIALS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	credentials, err := wssecurity.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	verifier := wssecurity.NewVerifier(credentials)
+	verifier.RequireTimestamp = os.Getenv("WSS_REQUIRE_TIMESTAMP") != ""
+	return verifier, nil
+}
+
+// requestLimits reads the limits of SOAP requests: SOAP_MAX_BODY_SIZE bytes,
+// elements nested SOAP_MAX_DEPTH deep and SOAP_MAX_ATTRIBUTES attributes per
+// element. Unset variables keep the default limits; 0 removes a limit.
+func requestLimits() (xmllimit.Limits, error) {
+	limits := xmllimit.Default
+	if value := os.Getenv("SOAP_MAX_BODY_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size < 0 {
+			return limits, fmt.Errorf("invalid SOAP_MAX_BODY_SIZE %q", value)
+		}
+		limits.MaxBodySize = size
+	}
+	if value := os.Getenv("SOAP_MAX_DEPTH"); value != "" {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return limits, fmt.Errorf("invalid SOAP_MAX_DEPTH %q", value)
+		}
+		limits.MaxDepth = depth
+	}
+	if value := os.Getenv("SOAP_MAX_ATTRIBUTES"); value != "" {
+		attributes, err := strconv.Atoi(value)
+		if err != nil || attributes < 0 {
+			return limits, fmt.Errorf("invalid SOAP_MAX_ATTRIBUTES %q", value)
+		}
+		limits.MaxAttributes = attributes
+	}
+	return limits, nil
+}
+
+func initializeRoutes(router *gin.Engine, rates *soap.MemoryRateProvider, service *soap.Service, document *wsdl.Document) {
 	// API v1 group
 	v1 := router.Group("/api/v1")
 	{
 		v1.GET("/health", healthCheck)
+
+		// The admin API changes the rates of every conversion, so it is only
+		// served to clients with the ADMIN_TOKEN, and not at all without one
+		if token := os.Getenv("ADMIN_TOKEN"); token != "" {
+			admin := v1.Group("/admin", requireAdminToken(token))
+			admin.GET("/rates", listRates(rates))
+			admin.PUT("/rates", updateRate(rates))
+		} else {
+			log.Println("ADMIN_TOKEN is not set, the admin API is disabled")
+		}
 	}
 
 	// SOAP endpoints
 	soapGroup := router.Group("/soap")
 	{
-		soapGroup.POST("/convert-currency", soap.HandleCurrencyConversion)
+		soapGroup.POST("/convert-currency", service.HandleCurrencyConversion)
+		// The WSDL is served at /soap/convert-currency?wsdl, with PUBLIC_URL
+		// as the service address when set
+		soapGroup.GET("/convert-currency", soap.WSDLHandler(document, "/soap/convert-currency", os.Getenv("PUBLIC_URL")))
 	}
 
 	// Swagger documentation
@@ -67,8 +292,44 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
+	// Initialize exchange rates
+	rates, err := newRateProvider()
+	if err != nil {
+		log.Fatal("Failed to load exchange rates:", err)
+	}
+
+	rounding, err := roundingMode()
+	if err != nil {
+		log.Fatal("Invalid rounding mode:", err)
+	}
+
+	schedule, err := pricingSchedule()
+	if err != nil {
+		log.Fatal("Failed to load pricing:", err)
+	}
+
+	// Generate the WSDL of the SOAP endpoint from its types
+	document, err := wsdl.Generate(soap.Description())
+	if err != nil {
+		log.Fatal("Failed to generate WSDL:", err)
+	}
+
+	verifier, err := securityVerifier()
+	if err != nil {
+		log.Fatal("Failed to load WS-Security credentials:", err)
+	}
+	limits, err := requestLimits()
+	if err != nil {
+		log.Fatal("Invalid request limits:", err)
+	}
+	service := soap.NewService(rates, iso4217.Default, schedule, rounding)
+	service.Limits = limits
+	if verifier != nil {
+		service.RequireAuthentication(verifier)
+	}
+
 	// Initialize routes
-	initializeRoutes(router)
+	initializeRoutes(router, rates, service, document)
 
 	// Get port from environment variable or use default
 	port := os.Getenv("PORT")
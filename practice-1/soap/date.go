@@ -0,0 +1,75 @@
+package soap
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayout is the xsd:date lexical form without a time zone
+const dateLayout = "2006-01-02"
+
+// Date is a calendar day, marshalled as xsd:date (YYYY-MM-DD)
+type Date struct {
+	t time.Time
+}
+
+// NewDate returns the calendar day of t in UTC
+func NewDate(t time.Time) Date {
+	t = t.UTC()
+	return Date{t: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+// Today returns the current calendar day in UTC
+func Today() Date {
+	return NewDate(time.Now())
+}
+
+// ParseDate reads a date in YYYY-MM-DD form
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	return Date{t: t}, nil
+}
+
+// Time returns midnight UTC of the date
+func (d Date) Time() time.Time {
+	return d.t
+}
+
+// Before reports whether d is an earlier day than o
+func (d Date) Before(o Date) bool {
+	return d.t.Before(o.t)
+}
+
+// After reports whether d is a later day than o
+func (d Date) After(o Date) bool {
+	return d.t.After(o.t)
+}
+
+// IsZero reports whether d is unset
+func (d Date) IsZero() bool {
+	return d.t.IsZero()
+}
+
+// String returns the date in YYYY-MM-DD form
+func (d Date) String() string {
+	return d.t.Format(dateLayout)
+}
+
+// MarshalText implements encoding.TextMarshaler, used for XML, JSON and YAML
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, used for XML, JSON and YAML
+func (d *Date) UnmarshalText(text []byte) error {
+	parsed, err := ParseDate(strings.TrimSpace(string(text)))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -2,124 +2,254 @@ package soap
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"net/http"
 
+	"iso4217"
+	"money"
+	"pricing"
+	"rates"
+	"soapfault"
+	"soapheader"
+	"wssecurity"
+	"xmllimit"
+
 	"github.com/gin-gonic/gin"
 )
 
-// Current exchange rates (for demo purposes)
-const (
-	UAHtoUSDRate = 0.025 // 1 UAH = 0.025 USD
-	USDtoUAHRate = 40.0  // 1 USD = 40 UAH
-)
+// Service serves the SOAP currency conversion endpoint
+type Service struct {
+	rates      RateProvider
+	currencies *iso4217.Registry
+	pricing    *pricing.Schedule
+	rounding   money.RoundingMode
 
-// HandleCurrencyConversion processes a SOAP currency conversion request
-func HandleCurrencyConversion(c *gin.Context) {
-	// Check content type
-	if c.GetHeader("Content-Type") != "text/xml" {
-		c.XML(http.StatusBadRequest, SOAPEnvelope{
-			Body: SOAPBody{
-				Fault: &SOAPFault{
-					FaultCode:   "Client",
-					FaultString: "Invalid Content-Type. Expected text/xml",
-				},
-			},
-		})
+	// Headers processes the entries of the SOAP Header of every request.
+	// Handlers registered with it add values to the context of the request
+	// and entries to the header of the response.
+	Headers *soapheader.Registry
+	// security authenticates requests when set, see RequireAuthentication
+	security *wssecurity.Verifier
+	// Limits are the size and XML limits of requests
+	Limits xmllimit.Limits
+}
+
+// NewService creates a SOAP service that validates currencies against the
+// registry, prices conversions with the given rate provider plus the spreads
+// and fees of the pricing schedule, and rounds amounts to the target
+// currency's minor units using the given mode
+func NewService(rates RateProvider, currencies *iso4217.Registry, schedule *pricing.Schedule, rounding money.RoundingMode) *Service {
+	return &Service{rates: rates, currencies: currencies, pricing: schedule, rounding: rounding,
+		Headers: soapheader.NewRegistry(), Limits: xmllimit.Default}
+}
+
+// RequireAuthentication authenticates every request by its WS-Security
+// header. The username is kept in the context of the request, see
+// wssecurity.UsernameFromContext.
+func (s *Service) RequireAuthentication(verifier *wssecurity.Verifier) {
+	s.Headers.Handle(wssecurity.Name, wssecurity.HandleHeader)
+	s.security = verifier
+}
+
+// HandleCurrencyConversion processes SOAP requests sent to the currency
+// conversion endpoint: ConvertCurrencyRequest and ListCurrenciesRequest
+func (s *Service) HandleCurrencyConversion(c *gin.Context) {
+	// Check content type; it selects the SOAP version and charset
+	ct, err := parseContentType(c.GetHeader("Content-Type"))
+	if err != nil {
+		sendFault(c, SOAP11, soapfault.New(soapfault.Client, soapfault.UnsupportedMediaType, "Unsupported media type",
+			fmt.Errorf("%w. Supported types: %s", err, supportedMediaTypes())))
 		return
 	}
+	version := ct.version
 
-	// Read the request body
-	body, err := io.ReadAll(c.Request.Body)
+	// Parse the SOAP envelope as it is read, stopping at the first limit
+	// the request exceeds
+	var envelope SOAPEnvelope
+	decoder, err := ct.newDecoder(s.Limits.Body(c.Writer, c.Request))
+	if err == nil {
+		err = xml.NewTokenDecoder(s.Limits.NewDecoder(decoder)).Decode(&envelope)
+	}
 	if err != nil {
-		c.XML(http.StatusBadRequest, SOAPEnvelope{
-			Body: SOAPBody{
-				Fault: &SOAPFault{
-					FaultCode:   "Client",
-					FaultString: "Failed to read request body",
-					Detail:      err.Error(),
-				},
-			},
-		})
+		var fault *soapfault.Error
+		if !errors.As(err, &fault) {
+			fault = soapfault.New(soapfault.Client, soapfault.MalformedRequest, "Failed to parse SOAP envelope", err)
+		}
+		sendFault(c, version, fault)
 		return
 	}
 
-	// Parse the SOAP envelope
-	var envelope SOAPEnvelope
-	if err := xml.Unmarshal(body, &envelope); err != nil {
-		c.XML(http.StatusBadRequest, SOAPEnvelope{
-			Body: SOAPBody{
-				Fault: &SOAPFault{
-					FaultCode:   "Client",
-					FaultString: "Failed to parse SOAP envelope",
-					Detail:      err.Error(),
-				},
-			},
-		})
+	// The envelope namespace must match the version announced by the Content-Type
+	if envelope.XMLName.Local != "Envelope" || versionForNamespace(envelope.XMLName.Space) != version {
+		sendFault(c, version, soapfault.New(soapfault.VersionMismatch, soapfault.UnsupportedEnvelope, "Unsupported SOAP envelope",
+			fmt.Errorf("expected a SOAP %s Envelope in namespace %s", version.Name, version.Namespace)))
 		return
 	}
 
-	// Check if we have a valid request
-	if envelope.Body.Request == nil {
-		c.XML(http.StatusBadRequest, SOAPEnvelope{
-			Body: SOAPBody{
-				Fault: &SOAPFault{
-					FaultCode:   "Client",
-					FaultString: "Missing currency conversion request",
-				},
-			},
-		})
+	// Process the header entries and authenticate the client
+	var entries []*soapheader.Entry
+	if envelope.Header != nil {
+		entries = envelope.Header.Entries
+	}
+	ctx, err := s.Headers.Process(c.Request.Context(), version.Namespace, entries)
+	c.Request = c.Request.WithContext(ctx)
+	if err != nil {
+		sendFault(c, version, soapfault.From(err))
 		return
 	}
+	if s.security != nil {
+		if ctx, err = s.security.Authenticate(ctx); err != nil {
+			log.Printf("soap: authentication failed: %v", err)
+			sendFault(c, version, soapfault.From(err))
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+	}
 
-	convRequest := envelope.Body.Request
+	// Dispatch on the operation in the body
+	switch {
+	case envelope.Body.Request != nil:
+		s.convert(c, version, envelope.Body.Request)
+	case envelope.Body.ListCurrencies != nil:
+		s.listCurrencies(c, version, envelope.Body.ListCurrencies)
+	default:
+		sendFault(c, version, soapfault.New(soapfault.Client, soapfault.UnknownOperation, "Missing request",
+			errors.New("expected ConvertCurrencyRequest or ListCurrenciesRequest")))
+	}
+}
 
+// convert handles a ConvertCurrencyRequest
+func (s *Service) convert(c *gin.Context, version *SOAPVersion, convRequest *ConvertCurrencyRequest) {
 	// Validate currencies
-	if !isValidCurrencyPair(convRequest.FromCurrency, convRequest.ToCurrency) {
-		c.XML(http.StatusBadRequest, SOAPEnvelope{
-			Body: SOAPBody{
-				Fault: &SOAPFault{
-					FaultCode:   "Client",
-					FaultString: "Invalid currency pair",
-					Detail:      fmt.Sprintf("Conversion from %s to %s is not supported", convRequest.FromCurrency, convRequest.ToCurrency),
-				},
-			},
-		})
+	if _, err := s.currencies.Validate(string(convRequest.FromCurrency)); err != nil {
+		sendFault(c, version, faultFor(fmt.Errorf("fromCurrency: %w", err)))
+		return
+	}
+	target, err := s.currencies.Validate(string(convRequest.ToCurrency))
+	if err != nil {
+		sendFault(c, version, faultFor(fmt.Errorf("toCurrency: %w", err)))
 		return
 	}
 
-	// Perform the conversion
-	rate, convertedAmount := convertCurrency(convRequest.Amount, convRequest.FromCurrency, convRequest.ToCurrency)
+	// Look up the rate, at the value date if one was given
+	rate, err := s.lookupRate(convRequest)
+	if err != nil {
+		sendFault(c, version, faultFor(err))
+		return
+	}
+
+	// Perform the conversion with the spread and fee for the pair
+	price, err := s.pricing.Price(convRequest.Amount, rate.Rate, string(convRequest.FromCurrency),
+		string(convRequest.ToCurrency), target.MinorUnits, RateScale, s.rounding)
+	if err != nil {
+		sendFault(c, version, faultFor(err))
+		return
+	}
 
 	// Create and send the response
-	c.Header("Content-Type", "text/xml")
-	c.XML(http.StatusOK, SOAPEnvelope{
-		Body: SOAPBody{
-			Response: &ConvertCurrencyResponse{
-				ConvertedAmount: convertedAmount,
-				FromCurrency:    convRequest.FromCurrency,
-				ToCurrency:      convRequest.ToCurrency,
-				Rate:            rate,
-			},
+	version.respond(c, http.StatusOK, SOAPBody{
+		Response: &ConvertCurrencyResponse{
+			ConvertedAmount: price.ConvertedAmount,
+			FromCurrency:    convRequest.FromCurrency,
+			ToCurrency:      convRequest.ToCurrency,
+			Rate:            price.AppliedRate,
+			Path:            rate.Path,
+			ValueDate:       convRequest.ValueDate,
+			RateDate:        rateDate(rate),
+			MidRate:         price.MidRate,
+			AppliedRate:     price.AppliedRate,
+			Fee:             price.Fee,
+			NetAmount:       price.NetAmount,
 		},
 	})
 }
 
-// isValidCurrencyPair checks if the currency pair is supported
-func isValidCurrencyPair(from, to Currency) bool {
-	return (from == UAH && to == USD) || (from == USD && to == UAH)
+// invalidValueDateError is returned for value dates that cannot be priced
+type invalidValueDateError struct {
+	reason string
+}
+
+func (e *invalidValueDateError) Error() string {
+	return e.reason
+}
+
+// lookupRate returns the current rate, or the historical rate if the
+// request has a value date
+func (s *Service) lookupRate(request *ConvertCurrencyRequest) (ExchangeRate, error) {
+	if request.ValueDate == nil {
+		return s.rates.Rate(request.FromCurrency, request.ToCurrency)
+	}
+
+	if request.ValueDate.After(Today()) {
+		return ExchangeRate{}, &invalidValueDateError{reason: fmt.Sprintf("valueDate %s is in the future", request.ValueDate)}
+	}
+	history, ok := s.rates.(HistoricalRateProvider)
+	if !ok {
+		return ExchangeRate{}, fmt.Errorf("historical rates are not available")
+	}
+	return history.RateOn(*request.ValueDate, request.FromCurrency, request.ToCurrency)
+}
+
+// rateDate returns the snapshot date of a rate, or nil if it has none
+func rateDate(rate ExchangeRate) *Date {
+	if rate.Date.IsZero() {
+		return nil
+	}
+	return &rate.Date
 }
 
-// convertCurrency performs the currency conversion
-func convertCurrency(amount float64, from, to Currency) (rate, convertedAmount float64) {
+// listCurrencies handles a ListCurrenciesRequest
+func (s *Service) listCurrencies(c *gin.Context, version *SOAPVersion, request *ListCurrenciesRequest) {
+	response := &ListCurrenciesResponse{}
+	for _, cur := range s.currencies.List(request.IncludeInactive) {
+		response.Currencies = append(response.Currencies, CurrencyInfo{
+			Code:        Currency(cur.Code),
+			NumericCode: cur.Numeric,
+			Name:        cur.Name,
+			MinorUnits:  cur.MinorUnits,
+			Active:      cur.Active,
+		})
+	}
+
+	version.respond(c, http.StatusOK, SOAPBody{
+		ListCurrenciesResponse: response,
+	})
+}
+
+// faultFor maps an error of a conversion to a SOAP fault. Errors caused by
+// the request are Client faults with the matching error code; any other
+// error is a Server fault.
+func faultFor(err error) *soapfault.Error {
+	var unknown *iso4217.UnknownCurrencyError
+	var inactive *iso4217.InactiveCurrencyError
+	var notFound *rates.NotFoundError
+	var invalidDate *invalidValueDateError
+	var noSnapshot *rates.NoSnapshotError
+	var invalidAmount *pricing.InvalidAmountError
+	var feeExceeds *pricing.FeeExceedsAmountError
 	switch {
-	case from == UAH && to == USD:
-		rate = UAHtoUSDRate
-		convertedAmount = amount * UAHtoUSDRate
-	case from == USD && to == UAH:
-		rate = USDtoUAHRate
-		convertedAmount = amount * USDtoUAHRate
-	}
-	return rate, convertedAmount
+	case errors.As(err, &unknown):
+		return soapfault.New(soapfault.Client, soapfault.UnknownCurrency, "Invalid currency", err)
+	case errors.As(err, &inactive):
+		return soapfault.New(soapfault.Client, soapfault.InactiveCurrency, "Invalid currency", err)
+	case errors.As(err, &notFound):
+		return soapfault.New(soapfault.Client, soapfault.RateNotFound, "Invalid currency pair", err)
+	case errors.As(err, &invalidDate):
+		return soapfault.New(soapfault.Client, soapfault.InvalidValueDate, "Invalid valueDate", err)
+	case errors.As(err, &noSnapshot):
+		return soapfault.New(soapfault.Client, soapfault.NoRatesForDate, "No exchange rates for valueDate", err)
+	case errors.As(err, &invalidAmount):
+		return soapfault.New(soapfault.Client, soapfault.InvalidAmount, "Invalid amount", err)
+	case errors.As(err, &feeExceeds):
+		return soapfault.New(soapfault.Client, soapfault.AmountTooSmall, "Amount too small", err)
+	default:
+		return soapfault.From(err)
+	}
+}
+
+// sendFault aborts the request with a SOAP fault in the given version
+func sendFault(c *gin.Context, version *SOAPVersion, fault *soapfault.Error) {
+	version.respond(c, fault.HTTPStatus(), version.fault(fault))
 }
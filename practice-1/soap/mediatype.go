@@ -0,0 +1,78 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// UnsupportedMediaTypeError is returned when the Content-Type of a request
+// is not one of the SOAP media types or names an unknown charset
+type UnsupportedMediaTypeError struct {
+	ContentType string
+	Reason      string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported Content-Type %q: %s", e.ContentType, e.Reason)
+}
+
+// supportedMediaTypes lists the accepted Content-Types for error messages
+func supportedMediaTypes() string {
+	return strings.Join([]string{SOAP11.MediaType, SOAP12.MediaType}, ", ")
+}
+
+// contentType is a parsed request Content-Type
+type contentType struct {
+	version *SOAPVersion
+	// charset is the lower-cased charset parameter, empty if absent
+	charset string
+}
+
+// parseContentType parses a Content-Type header such as
+// `text/xml; charset=utf-8` or `application/soap+xml; charset=utf-8; action="..."`
+func parseContentType(header string) (contentType, error) {
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return contentType{}, &UnsupportedMediaTypeError{ContentType: header, Reason: err.Error()}
+	}
+
+	version := versionForMediaType(mediaType)
+	if version == nil {
+		return contentType{}, &UnsupportedMediaTypeError{ContentType: header, Reason: "not a SOAP media type"}
+	}
+
+	ct := contentType{version: version, charset: strings.ToLower(params["charset"])}
+	if ct.charset != "" {
+		if enc, _ := charset.Lookup(ct.charset); enc == nil {
+			return contentType{}, &UnsupportedMediaTypeError{ContentType: header, Reason: fmt.Sprintf("unknown charset %q", ct.charset)}
+		}
+	}
+	return ct, nil
+}
+
+// newDecoder returns an XML decoder for a request body in the given charset.
+// Bodies in a non-UTF-8 charset from the Content-Type are transcoded up front;
+// otherwise the encoding in the XML declaration, if any, is honoured.
+func (ct contentType) newDecoder(body io.Reader) (*xml.Decoder, error) {
+	if ct.charset == "" || ct.charset == "utf-8" {
+		decoder := xml.NewDecoder(body)
+		decoder.CharsetReader = charset.NewReaderLabel
+		return decoder, nil
+	}
+
+	utf8Body, err := charset.NewReaderLabel(ct.charset, body)
+	if err != nil {
+		return nil, err
+	}
+	decoder := xml.NewDecoder(utf8Body)
+	// The body is already UTF-8, whatever its XML declaration says
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder, nil
+}
@@ -0,0 +1,262 @@
+package soap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"money"
+	"rates"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RateScale is the number of fraction digits derived cross rates are rounded to
+const RateScale = rates.Scale
+
+// RateProvider supplies exchange rates to the conversion handler
+type RateProvider interface {
+	// Rate returns how many units of "to" one unit of "from" buys
+	Rate(from, to Currency) (ExchangeRate, error)
+}
+
+// HistoricalRateProvider is implemented by providers that keep dated rate
+// snapshots and can price a conversion at a past value date
+type HistoricalRateProvider interface {
+	RateProvider
+	// RateOn returns the rate that applied on the given date
+	RateOn(date Date, from, to Currency) (ExchangeRate, error)
+}
+
+// ExchangeRate is a rate between two currencies together with the
+// currencies it was derived through
+type ExchangeRate struct {
+	Rate money.Decimal
+	// Path lists the currencies the rate was derived through, starting
+	// with the source and ending with the target currency
+	Path []Currency
+	// Date is the effective date of the snapshot the rate came from,
+	// zero for providers without dated snapshots
+	Date Date
+}
+
+// RateTable stores exchange rates against a single base currency.
+// Rates[c] is how many units of c one unit of Base buys; any cross rate
+// between two listed currencies is derived through the base.
+type RateTable struct {
+	Base  Currency                   `json:"base" yaml:"base"`
+	Rates map[Currency]money.Decimal `json:"rates" yaml:"rates" swaggertype:"object,number"`
+}
+
+// DefaultRates are the demo rates the service starts with
+var DefaultRates = RateTable{
+	Base: USD,
+	Rates: map[Currency]money.Decimal{
+		UAH: money.MustParse("40"),   // 1 USD = 40 UAH
+		EUR: money.MustParse("0.93"), // 1 USD = 0.93 EUR
+		GBP: money.MustParse("0.79"), // 1 USD = 0.79 GBP
+		JPY: money.MustParse("152"),  // 1 USD = 152 JPY
+	},
+}
+
+// table returns the table as rates of the shared engine, checking that it
+// has a base and every rate is positive
+func (t RateTable) table() (*rates.Table, error) {
+	if t.Base == "" {
+		return nil, fmt.Errorf("rate table has no base currency")
+	}
+	baseRates := make(map[string]money.Decimal, len(t.Rates))
+	for c, rate := range t.Rates {
+		baseRates[string(c)] = rate
+	}
+	return rates.NewTable(string(t.Base), baseRates)
+}
+
+// rateTable returns a table of the shared engine as a RateTable
+func rateTable(table *rates.Table) RateTable {
+	out := RateTable{Base: Currency(table.Base()), Rates: make(map[Currency]money.Decimal)}
+	for code, rate := range table.Rates() {
+		out.Rates[Currency(code)] = rate
+	}
+	return out
+}
+
+// exchangeRate returns a rate of the shared engine as an ExchangeRate
+func exchangeRate(rate rates.Rate) ExchangeRate {
+	path := make([]Currency, len(rate.Path))
+	for i, code := range rate.Path {
+		path[i] = Currency(code)
+	}
+	out := ExchangeRate{Rate: rate.Value, Path: path}
+	if !rate.Date.IsZero() {
+		out.Date = NewDate(rate.Date)
+	}
+	return out
+}
+
+// RateSnapshot is a rate table effective from a date until the next snapshot
+type RateSnapshot struct {
+	Date      Date `json:"date" yaml:"date"`
+	RateTable `yaml:",inline"`
+}
+
+// StaticRateProvider serves rates from a fixed table
+type StaticRateProvider struct {
+	table *rates.Table
+}
+
+// NewStaticRateProvider creates a provider backed by a copy of the given table
+func NewStaticRateProvider(table RateTable) (*StaticRateProvider, error) {
+	t, err := table.table()
+	if err != nil {
+		return nil, err
+	}
+	return &StaticRateProvider{table: t}, nil
+}
+
+// Rate implements RateProvider
+func (p *StaticRateProvider) Rate(from, to Currency) (ExchangeRate, error) {
+	rate, err := p.table.Rate(string(from), string(to))
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+	return exchangeRate(rate), nil
+}
+
+// LoadRateFile reads a rate table from a JSON or YAML file.
+// The format is chosen by the file extension (.json, .yaml or .yml).
+func LoadRateFile(path string) (RateTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RateTable{}, err
+	}
+
+	var table RateTable
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &table)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &table)
+	default:
+		return RateTable{}, fmt.Errorf("unsupported rate file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return RateTable{}, fmt.Errorf("failed to parse rate file %s: %w", path, err)
+	}
+
+	if _, err := table.table(); err != nil {
+		return RateTable{}, err
+	}
+	return table, nil
+}
+
+// LoadRateHistoryFile reads dated rate snapshots from a JSON or YAML file.
+// The file holds a list of rate tables, each with a "date" in YYYY-MM-DD form.
+func LoadRateHistoryFile(path string) ([]RateSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []RateSnapshot
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &snapshots)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &snapshots)
+	default:
+		return nil, fmt.Errorf("unsupported rate file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rate history file %s: %w", path, err)
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.Date.IsZero() {
+			return nil, fmt.Errorf("rate snapshot without a date in %s", path)
+		}
+		if _, err := snapshot.table(); err != nil {
+			return nil, fmt.Errorf("rate snapshot for %s: %w", snapshot.Date, err)
+		}
+	}
+	return snapshots, nil
+}
+
+// NewFileRateProvider creates a static provider from a JSON or YAML rate file
+func NewFileRateProvider(path string) (*StaticRateProvider, error) {
+	table, err := LoadRateFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewStaticRateProvider(table)
+}
+
+// MemoryRateProvider is an in-memory rate store that can be updated at runtime.
+// It keeps dated snapshots of the rate table so conversions can be priced
+// at a past value date.
+type MemoryRateProvider struct {
+	engine *rates.Engine
+}
+
+// NewMemoryRateProvider creates an updatable provider seeded with the given
+// table, effective from today
+func NewMemoryRateProvider(seed RateTable) (*MemoryRateProvider, error) {
+	table, err := seed.table()
+	if err != nil {
+		return nil, err
+	}
+	engine, err := rates.NewEngine(table.Base(), table.Rates())
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryRateProvider{engine: engine}, nil
+}
+
+// Rate implements RateProvider using the current rates
+func (p *MemoryRateProvider) Rate(from, to Currency) (ExchangeRate, error) {
+	rate, err := p.engine.Rate(string(from), string(to))
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+	return exchangeRate(rate), nil
+}
+
+// RateOn implements HistoricalRateProvider
+func (p *MemoryRateProvider) RateOn(date Date, from, to Currency) (ExchangeRate, error) {
+	rate, err := p.engine.RateOn(date.Time(), string(from), string(to))
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+	return exchangeRate(rate), nil
+}
+
+// SetRate adds or replaces the rate of a currency against the base currency.
+// The change takes effect from today; earlier snapshots are left untouched.
+func (p *MemoryRateProvider) SetRate(c Currency, rate money.Decimal) error {
+	return p.engine.Set(string(c), rate)
+}
+
+// Replace swaps the whole rate table with effect from today
+func (p *MemoryRateProvider) Replace(table RateTable) error {
+	return p.AddSnapshot(RateSnapshot{Date: Today(), RateTable: table})
+}
+
+// AddSnapshot stores a rate table effective from the snapshot's date,
+// replacing any snapshot for the same date
+func (p *MemoryRateProvider) AddSnapshot(snapshot RateSnapshot) error {
+	if snapshot.Date.IsZero() {
+		return fmt.Errorf("rate snapshot has no date")
+	}
+	table, err := snapshot.table()
+	if err != nil {
+		return fmt.Errorf("rate snapshot for %s: %w", snapshot.Date, err)
+	}
+	return p.engine.AddSnapshot(rates.Snapshot{Date: snapshot.Date.Time(), Table: table})
+}
+
+// Snapshot returns a copy of the current rate table
+func (p *MemoryRateProvider) Snapshot() RateTable {
+	return rateTable(p.engine.Current().Table)
+}
@@ -1,50 +1,120 @@
 package soap
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+
+	"money"
+	"soapfault"
+	"soapheader"
+)
 
 const (
 	USD Currency = "USD"
 	UAH Currency = "UAH"
+	EUR Currency = "EUR"
+	GBP Currency = "GBP"
+	JPY Currency = "JPY"
 )
 
+// Namespace is the target namespace of the service's request and response
+// elements. Requests are accepted in any namespace.
+const Namespace = "http://practice-1/soap"
+
 // Currency represents a currency type
 type Currency string
 
-// SOAPEnvelope represents the SOAP envelope
+// SOAPEnvelope represents the SOAP envelope.
+// The namespace of XMLName selects the SOAP version (1.1 or 1.2).
 type SOAPEnvelope struct {
-	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	XMLName xml.Name
+	Header  *SOAPHeader `xml:"Header,omitempty"`
 	Body    SOAPBody
 }
 
-// SOAPBody represents the SOAP body
-type SOAPBody struct {
-	XMLName  xml.Name                 `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
-	Fault    *SOAPFault               `xml:",omitempty"`
-	Request  *ConvertCurrencyRequest  `xml:",omitempty"`
-	Response *ConvertCurrencyResponse `xml:",omitempty"`
+// SOAPHeader holds the header entries of a request, or those of a response
+type SOAPHeader struct {
+	XMLName xml.Name
+	// Entries are the entries of a request, processed by soapheader.Registry
+	Entries []*soapheader.Entry `xml:"-"`
+	// Response are the entries of a response
+	Response []interface{}
 }
 
-// SOAPFault represents a SOAP fault
-type SOAPFault struct {
-	XMLName     xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault"`
-	FaultCode   string   `xml:"faultcode"`
-	FaultString string   `xml:"faultstring"`
-	Detail      string   `xml:"detail,omitempty"`
+// UnmarshalXML implements xml.Unmarshaler, keeping the entries of the
+// header to be processed once the SOAP version is known
+func (h *SOAPHeader) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	entries, err := soapheader.ReadEntries(d)
+	if err != nil {
+		return err
+	}
+	h.XMLName = start.Name
+	h.Entries = entries
+	return nil
+}
+
+// SOAPBody represents the SOAP body
+type SOAPBody struct {
+	XMLName                xml.Name
+	Fault                  *soapfault.SOAP11Fault   `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault,omitempty"`
+	Fault12                *soapfault.SOAP12Fault   `xml:"http://www.w3.org/2003/05/soap-envelope Fault,omitempty"`
+	Request                *ConvertCurrencyRequest  `xml:",omitempty"`
+	Response               *ConvertCurrencyResponse `xml:",omitempty"`
+	ListCurrencies         *ListCurrenciesRequest   `xml:",omitempty"`
+	ListCurrenciesResponse *ListCurrenciesResponse  `xml:",omitempty"`
 }
 
 // ConvertCurrencyRequest represents a currency conversion request
 type ConvertCurrencyRequest struct {
-	XMLName      xml.Name `xml:"ConvertCurrencyRequest"`
-	Amount       float64  `xml:"amount"`
-	FromCurrency Currency `xml:"fromCurrency"`
-	ToCurrency   Currency `xml:"toCurrency"`
+	XMLName      xml.Name      `xml:"ConvertCurrencyRequest"`
+	Amount       money.Decimal `xml:"amount"`
+	FromCurrency Currency      `xml:"fromCurrency"`
+	ToCurrency   Currency      `xml:"toCurrency"`
+	// ValueDate prices the conversion at the rates that applied on that day
+	ValueDate *Date `xml:"valueDate,omitempty"`
 }
 
 // ConvertCurrencyResponse represents a currency conversion response
 type ConvertCurrencyResponse struct {
-	XMLName         xml.Name `xml:"ConvertCurrencyResponse"`
-	ConvertedAmount float64  `xml:"convertedAmount"`
-	FromCurrency    Currency `xml:"fromCurrency"`
-	ToCurrency      Currency `xml:"toCurrency"`
-	Rate            float64  `xml:"rate"`
+	XMLName xml.Name `xml:"http://practice-1/soap ConvertCurrencyResponse"`
+	// ConvertedAmount is the amount at the applied rate, before fees
+	ConvertedAmount money.Decimal `xml:"convertedAmount"`
+	FromCurrency    Currency      `xml:"fromCurrency"`
+	ToCurrency      Currency      `xml:"toCurrency"`
+	// Rate is the rate the amount was converted at, the same as AppliedRate
+	Rate money.Decimal `xml:"rate"`
+	// Path lists the currencies the rate was derived through
+	Path []Currency `xml:"path>currency,omitempty"`
+	// ValueDate echoes the requested value date
+	ValueDate *Date `xml:"valueDate,omitempty"`
+	// RateDate is the effective date of the rate snapshot that was used
+	RateDate *Date `xml:"rateDate,omitempty"`
+	// MidRate is the market rate before the spread
+	MidRate money.Decimal `xml:"midRate"`
+	// AppliedRate is the mid rate after the spread
+	AppliedRate money.Decimal `xml:"appliedRate"`
+	// Fee is charged in the target currency
+	Fee money.Decimal `xml:"fee"`
+	// NetAmount is the converted amount less the fee
+	NetAmount money.Decimal `xml:"netAmount"`
+}
+
+// ListCurrenciesRequest asks for the currencies the service supports
+type ListCurrenciesRequest struct {
+	XMLName         xml.Name `xml:"ListCurrenciesRequest"`
+	IncludeInactive bool     `xml:"includeInactive,omitempty"`
+}
+
+// ListCurrenciesResponse lists the currencies the service supports
+type ListCurrenciesResponse struct {
+	XMLName    xml.Name       `xml:"http://practice-1/soap ListCurrenciesResponse"`
+	Currencies []CurrencyInfo `xml:"currency"`
+}
+
+// CurrencyInfo describes an ISO 4217 currency
+type CurrencyInfo struct {
+	Code        Currency `xml:"code"`
+	NumericCode string   `xml:"numericCode"`
+	Name        string   `xml:"name"`
+	MinorUnits  int      `xml:"minorUnits"`
+	Active      bool     `xml:"active"`
 }
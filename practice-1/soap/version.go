@@ -0,0 +1,86 @@
+package soap
+
+import (
+	"encoding/xml"
+
+	"soapfault"
+	"soapheader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Envelope namespaces of the supported SOAP versions
+const (
+	SOAP11Namespace = soapfault.SOAP11Namespace
+	SOAP12Namespace = soapfault.SOAP12Namespace
+)
+
+// SOAPVersion describes the wire format of a SOAP version
+type SOAPVersion struct {
+	// Name is the version number, e.g. "1.2"
+	Name string
+	// Namespace is the envelope namespace
+	Namespace string
+	// MediaType is the Content-Type used for messages of this version
+	MediaType string
+}
+
+// Supported SOAP versions
+var (
+	SOAP11 = &SOAPVersion{Name: "1.1", Namespace: SOAP11Namespace, MediaType: "text/xml"}
+	SOAP12 = &SOAPVersion{Name: "1.2", Namespace: SOAP12Namespace, MediaType: "application/soap+xml"}
+)
+
+// versionForMediaType returns the SOAP version that uses the given media type
+func versionForMediaType(mediaType string) *SOAPVersion {
+	for _, v := range []*SOAPVersion{SOAP11, SOAP12} {
+		if v.MediaType == mediaType {
+			return v
+		}
+	}
+	return nil
+}
+
+// versionForNamespace returns the SOAP version with the given envelope namespace
+func versionForNamespace(namespace string) *SOAPVersion {
+	for _, v := range []*SOAPVersion{SOAP11, SOAP12} {
+		if v.Namespace == namespace {
+			return v
+		}
+	}
+	return nil
+}
+
+// envelope wraps a body, and the header entries if there are any, in an
+// envelope of this version
+func (v *SOAPVersion) envelope(header []interface{}, body SOAPBody) SOAPEnvelope {
+	body.XMLName = xml.Name{Space: v.Namespace, Local: "Body"}
+	envelope := SOAPEnvelope{
+		XMLName: xml.Name{Space: v.Namespace, Local: "Envelope"},
+		Body:    body,
+	}
+	if len(header) > 0 {
+		envelope.Header = &SOAPHeader{
+			XMLName:  xml.Name{Space: v.Namespace, Local: "Header"},
+			Response: header,
+		}
+	}
+	return envelope
+}
+
+// fault builds the version specific fault element. Fault codes are
+// qualified: soap:Client and soap:Server in SOAP 1.1, env:Sender and
+// env:Receiver in SOAP 1.2.
+func (v *SOAPVersion) fault(fault *soapfault.Error) SOAPBody {
+	if v != SOAP12 {
+		return SOAPBody{Fault: fault.SOAP11()}
+	}
+	return SOAPBody{Fault12: fault.SOAP12()}
+}
+
+// respond writes a SOAP envelope of this version with the matching
+// Content-Type and the response header entries of the request
+func (v *SOAPVersion) respond(c *gin.Context, status int, body SOAPBody) {
+	c.Header("Content-Type", v.MediaType+"; charset=utf-8")
+	c.XML(status, v.envelope(soapheader.ResponseHeaders(c.Request.Context()), body))
+}
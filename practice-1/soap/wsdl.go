@@ -0,0 +1,60 @@
+package soap
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"money"
+	"practice-1/wsdl"
+	"soapfault"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Description describes the operations of the currency conversion endpoint
+// for WSDL generation
+func Description() wsdl.Service {
+	return wsdl.Service{
+		Name:      "CurrencyConversionService",
+		Namespace: Namespace,
+		Operations: []wsdl.Operation{
+			{Name: "ConvertCurrency", Request: ConvertCurrencyRequest{}, Response: ConvertCurrencyResponse{}},
+			{Name: "ListCurrencies", Request: ListCurrenciesRequest{}, Response: ListCurrenciesResponse{}},
+		},
+		Fault: soapfault.Detail{},
+		SimpleTypes: map[reflect.Type]string{
+			reflect.TypeOf(money.Decimal{}): "decimal",
+			reflect.TypeOf(Date{}):          "date",
+		},
+	}
+}
+
+// WSDLHandler serves the WSDL document of the endpoint at path for GET
+// requests with a ?wsdl query. The service address is baseURL followed by
+// path, or if baseURL is empty, the scheme and host of the request.
+func WSDLHandler(document *wsdl.Document, path, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		if !query.Has("wsdl") && !query.Has("WSDL") {
+			c.Header("Allow", http.MethodPost)
+			c.String(http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		base := strings.TrimSuffix(baseURL, "/")
+		if base == "" {
+			scheme := "http"
+			if c.Request.TLS != nil {
+				scheme = "https"
+			}
+			base = scheme + "://" + c.Request.Host
+		}
+		data, err := document.Render(base + path)
+		if err != nil {
+			c.String(http.StatusInternalServerError, "Error rendering WSDL")
+			return
+		}
+		c.Data(http.StatusOK, "text/xml; charset=utf-8", data)
+	}
+}
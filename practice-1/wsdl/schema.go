@@ -0,0 +1,231 @@
+package wsdl
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// element is an XML Schema element declaration
+type element struct {
+	Name string
+	// Type is the qualified name of a built-in or named type, e.g. xsd:decimal;
+	// it is empty for elements with an anonymous complex type
+	Type      string
+	MinOccurs string
+	MaxOccurs string
+	// Fields are the children of an anonymous complex type
+	Fields []*element
+}
+
+// complexType is a named complex type, derived from a Go struct type
+type complexType struct {
+	Name   string
+	Fields []*element
+}
+
+// schema is an XML Schema for one target namespace
+type schema struct {
+	Namespace string
+	// Prefix is bound to the namespace in the document, e.g. tns
+	Prefix   string
+	Elements []*element
+	Types    []*complexType
+}
+
+// builtins are the XML Schema types of Go kinds that marshal as text
+var builtins = map[reflect.Kind]string{
+	reflect.String:  "string",
+	reflect.Bool:    "boolean",
+	reflect.Int:     "long",
+	reflect.Int8:    "byte",
+	reflect.Int16:   "short",
+	reflect.Int32:   "int",
+	reflect.Int64:   "long",
+	reflect.Uint:    "unsignedLong",
+	reflect.Uint8:   "unsignedByte",
+	reflect.Uint16:  "unsignedShort",
+	reflect.Uint32:  "unsignedInt",
+	reflect.Uint64:  "unsignedLong",
+	reflect.Float32: "float",
+	reflect.Float64: "double",
+}
+
+var (
+	xmlNameType       = reflect.TypeOf(xml.Name{})
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaBuilder derives a schema from Go types
+type schemaBuilder struct {
+	schema      *schema
+	simpleTypes map[reflect.Type]string
+	// named holds the complex types already derived, by Go type
+	named map[reflect.Type]*complexType
+}
+
+func newSchemaBuilder(namespace, prefix string, simpleTypes map[reflect.Type]string) *schemaBuilder {
+	return &schemaBuilder{
+		schema:      &schema{Namespace: namespace, Prefix: prefix},
+		simpleTypes: simpleTypes,
+		named:       make(map[reflect.Type]*complexType),
+	}
+}
+
+// global adds the global element of a struct value whose XMLName names it
+// and returns the element's name
+func (b *schemaBuilder) global(value interface{}) (string, error) {
+	t := reflect.TypeOf(value)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("%v is not a struct", t)
+	}
+	name, err := elementName(t)
+	if err != nil {
+		return "", err
+	}
+	if name.Space != "" && name.Space != b.schema.Namespace {
+		return "", fmt.Errorf("%s: element %s is in namespace %q, expected %q", t, name.Local, name.Space, b.schema.Namespace)
+	}
+	for _, e := range b.schema.Elements {
+		if e.Name == name.Local {
+			return "", fmt.Errorf("%s: duplicate element %s", t, name.Local)
+		}
+	}
+
+	fields, err := b.fields(t)
+	if err != nil {
+		return "", err
+	}
+	b.schema.Elements = append(b.schema.Elements, &element{Name: name.Local, Fields: fields})
+	return name.Local, nil
+}
+
+// fields derives the sequence of child elements of a struct type
+func (b *schemaBuilder) fields(t reflect.Type) ([]*element, error) {
+	var fields []*element
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Type == xmlNameType {
+			continue
+		}
+		tag := f.Tag.Get("xml")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if options != "" && options != "omitempty" {
+			return nil, fmt.Errorf("%s.%s: xml option %q is not supported", t, f.Name, options)
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		e, err := b.field(f.Type, name, options == "omitempty")
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t, f.Name, err)
+		}
+		fields = append(fields, e)
+	}
+	return fields, nil
+}
+
+// field derives the element of a struct field. A name of the form a>b
+// declares a wrapper element a around the b elements.
+func (b *schemaBuilder) field(t reflect.Type, name string, optional bool) (*element, error) {
+	if wrapper, inner, ok := strings.Cut(name, ">"); ok {
+		child, err := b.field(t, inner, false)
+		if err != nil {
+			return nil, err
+		}
+		e := &element{Name: wrapper, Fields: []*element{child}}
+		if optional || t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+			e.MinOccurs = "0"
+		}
+		return e, nil
+	}
+	if strings.Contains(name, " ") {
+		return nil, fmt.Errorf("namespaced child element %q is not supported", name)
+	}
+
+	e := &element{Name: name}
+	if optional {
+		e.MinOccurs = "0"
+	}
+	if t.Kind() == reflect.Pointer {
+		e.MinOccurs = "0"
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		e.MinOccurs, e.MaxOccurs = "0", "unbounded"
+		t = t.Elem()
+	}
+
+	var err error
+	e.Type, err = b.typeName(t)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// typeName returns the qualified name of the schema type of a Go type
+func (b *schemaBuilder) typeName(t reflect.Type) (string, error) {
+	if name, ok := b.simpleTypes[t]; ok {
+		return "xsd:" + name, nil
+	}
+	if t == timeType {
+		return "xsd:dateTime", nil
+	}
+	if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+		return "xsd:base64Binary", nil
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return "", fmt.Errorf("no XML Schema type is given for %s", t)
+	}
+	if name, ok := builtins[t.Kind()]; ok {
+		return "xsd:" + name, nil
+	}
+	if t.Kind() != reflect.Struct || t.Name() == "" {
+		return "", fmt.Errorf("type %s is not supported", t)
+	}
+
+	if complex, ok := b.named[t]; ok {
+		return b.schema.Prefix + ":" + complex.Name, nil
+	}
+	for _, complex := range b.schema.Types {
+		if complex.Name == t.Name() {
+			return "", fmt.Errorf("%s: another type is also named %s", t, t.Name())
+		}
+	}
+	// Register before deriving the fields so recursive types resolve
+	complex := &complexType{Name: t.Name()}
+	b.named[t] = complex
+	fields, err := b.fields(t)
+	if err != nil {
+		return "", err
+	}
+	complex.Fields = fields
+	b.schema.Types = append(b.schema.Types, complex)
+	return b.schema.Prefix + ":" + complex.Name, nil
+}
+
+// elementName reads the element name from the XMLName tag of a struct type
+func elementName(t reflect.Type) (xml.Name, error) {
+	if field, ok := t.FieldByName("XMLName"); ok && field.Type == xmlNameType {
+		tag, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+		if space, local, ok := strings.Cut(tag, " "); ok {
+			return xml.Name{Space: space, Local: local}, nil
+		}
+		if tag != "" {
+			return xml.Name{Local: tag}, nil
+		}
+	}
+	return xml.Name{}, fmt.Errorf("%s has no XMLName tag naming its element", t)
+}
@@ -0,0 +1,165 @@
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// binding is a SOAP binding of the port type, with its port
+type binding struct {
+	// Prefix is bound to the namespace of the binding extension elements
+	Prefix string
+	// Suffix is appended to the base name of the service
+	Suffix string
+	// Comment precedes the binding in the document
+	Comment string
+}
+
+// bindings are the SOAP 1.1 and SOAP 1.2 bindings of every document
+var bindings = []binding{
+	{Prefix: "soap", Suffix: "", Comment: "SOAP 1.1 binding"},
+	{Prefix: "soap12", Suffix: "Soap12", Comment: "SOAP 1.2 binding"},
+}
+
+// documentTemplate writes a Document in the layout of a hand-written WSDL
+var documentTemplate = template.Must(template.New("wsdl").Funcs(template.FuncMap{
+	"attr":     attr,
+	"elements": elements,
+	"bindings": func() []binding { return bindings },
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- Generated from the Go types of the service -->
+<definitions
+    name="{{.Name}}"
+    targetNamespace="{{attr .Namespace}}"
+    xmlns="http://schemas.xmlsoap.org/wsdl/"
+    xmlns:soap="http://schemas.xmlsoap.org/wsdl/soap/"
+    xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+    xmlns:xsd="http://www.w3.org/2001/XMLSchema"
+{{- range .Schemas}}
+    xmlns:{{.Prefix}}="{{attr .Namespace}}"
+{{- end}}>
+
+    <!-- Types definition -->
+    <types>
+{{- range .Schemas}}
+        <xsd:schema targetNamespace="{{attr .Namespace}}" elementFormDefault="qualified">
+{{elements .Elements 3}}
+{{- range .Types}}
+            <xsd:complexType name="{{.Name}}">
+                <xsd:sequence>
+{{elements .Fields 5}}
+                </xsd:sequence>
+            </xsd:complexType>
+{{- end}}
+        </xsd:schema>
+{{- end}}
+    </types>
+
+    <!-- Message definitions -->
+{{- range .Operations}}
+    <message name="{{.Name}}Input">
+        <part name="parameters" element="{{.Request}}" />
+    </message>
+    <message name="{{.Name}}Output">
+        <part name="parameters" element="{{.Response}}" />
+    </message>
+{{- end}}
+{{- if .Fault}}
+    <message name="ServiceFault">
+        <part name="detail" element="{{.Fault}}" />
+    </message>
+{{- end}}
+
+    <!-- Port Type -->
+    <portType name="{{.BaseName}}PortType">
+{{- range .Operations}}
+        <operation name="{{.Name}}">
+            <input message="tns:{{.Name}}Input" />
+            <output message="tns:{{.Name}}Output" />
+{{- if $.Fault}}
+            <fault name="ServiceFault" message="tns:ServiceFault" />
+{{- end}}
+        </operation>
+{{- end}}
+    </portType>
+{{range bindings}}
+    <!-- {{.Comment}} -->
+    <binding name="{{$.BaseName}}{{.Suffix}}Binding" type="tns:{{$.BaseName}}PortType">
+        <{{.Prefix}}:binding style="document" transport="http://schemas.xmlsoap.org/soap/http" />
+{{- $prefix := .Prefix}}
+{{- range $.Operations}}
+        <operation name="{{.Name}}">
+            <{{$prefix}}:operation soapAction="{{attr .Action}}" />
+            <input>
+                <{{$prefix}}:body use="literal" />
+            </input>
+            <output>
+                <{{$prefix}}:body use="literal" />
+            </output>
+{{- if $.Fault}}
+            <fault name="ServiceFault">
+                <{{$prefix}}:fault name="ServiceFault" use="literal" />
+            </fault>
+{{- end}}
+        </operation>
+{{- end}}
+    </binding>
+{{end}}
+    <!-- Service -->
+    <service name="{{.Name}}">
+{{- range bindings}}
+        <port name="{{$.BaseName}}{{.Suffix}}Port" binding="tns:{{$.BaseName}}{{.Suffix}}Binding">
+            <{{.Prefix}}:address location="{{attr $.Address}}" />
+        </port>
+{{- end}}
+    </service>
+</definitions>
+`))
+
+// attr escapes an attribute value
+func attr(value string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
+
+// elements writes element declarations, nesting anonymous complex types,
+// indented by the given number of levels
+func elements(list []*element, level int) string {
+	var out strings.Builder
+	for i, e := range list {
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+		writeElement(&out, e, level)
+	}
+	return out.String()
+}
+
+func writeElement(out *strings.Builder, e *element, level int) {
+	indent := strings.Repeat("    ", level)
+	fmt.Fprintf(out, `%s<xsd:element name="%s"`, indent, e.Name)
+	if e.Type != "" {
+		fmt.Fprintf(out, ` type="%s"`, e.Type)
+	}
+	if e.MinOccurs != "" {
+		fmt.Fprintf(out, ` minOccurs="%s"`, e.MinOccurs)
+	}
+	if e.MaxOccurs != "" {
+		fmt.Fprintf(out, ` maxOccurs="%s"`, e.MaxOccurs)
+	}
+	if e.Type != "" {
+		out.WriteString(" />")
+		return
+	}
+
+	fmt.Fprintf(out, ">\n%s    <xsd:complexType>\n%s        <xsd:sequence>\n", indent, indent)
+	for _, field := range e.Fields {
+		writeElement(out, field, level+3)
+		out.WriteByte('\n')
+	}
+	fmt.Fprintf(out, "%s        </xsd:sequence>\n%s    </xsd:complexType>\n%s</xsd:element>", indent, indent, indent)
+}
@@ -0,0 +1,134 @@
+// Package wsdl generates WSDL 1.1 documents for document/literal SOAP
+// services from the Go types of their request and response elements.
+package wsdl
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Operation is a document/literal operation
+type Operation struct {
+	Name string
+	// Request and Response are struct values, or pointers to them, whose
+	// XMLName field names the element
+	Request  interface{}
+	Response interface{}
+}
+
+// Service describes the service a WSDL document is generated for
+type Service struct {
+	// Name is the WSDL service name, e.g. CurrencyConversionService; the
+	// port type, bindings and ports are named after it
+	Name string
+	// Namespace is the target namespace of the document and its elements
+	Namespace  string
+	Operations []Operation
+	// Fault, if set, is the struct value of the element in the detail of
+	// the service's faults; its XMLName must give its namespace
+	Fault interface{}
+	// SimpleTypes gives the built-in XML Schema type, e.g. decimal, of Go
+	// types that marshal as text
+	SimpleTypes map[reflect.Type]string
+}
+
+// Document is a generated WSDL document; the address of the service is
+// filled in by Render
+type Document struct {
+	Name       string
+	Namespace  string
+	Schemas    []*schema
+	Operations []operation
+	// Fault is the qualified name of the fault detail element, if any
+	Fault string
+}
+
+// operation is an operation with the names of its elements
+type operation struct {
+	Name     string
+	Action   string
+	Request  string
+	Response string
+}
+
+// Generate derives a WSDL document with SOAP 1.1 and SOAP 1.2 bindings from
+// the request and response types of the service's operations. Struct fields
+// become child elements in field order, with their xml tag names; pointer,
+// slice and omitempty fields are optional, slices repeat, and named struct
+// types become named complex types.
+func Generate(service Service) (*Document, error) {
+	if service.Name == "" || service.Namespace == "" {
+		return nil, fmt.Errorf("service name and namespace are required")
+	}
+	if len(service.Operations) == 0 {
+		return nil, fmt.Errorf("service %s has no operations", service.Name)
+	}
+
+	doc := &Document{Name: service.Name, Namespace: service.Namespace}
+	builder := newSchemaBuilder(service.Namespace, "tns", service.SimpleTypes)
+	for _, op := range service.Operations {
+		request, err := builder.global(op.Request)
+		if err != nil {
+			return nil, fmt.Errorf("operation %s: %w", op.Name, err)
+		}
+		response, err := builder.global(op.Response)
+		if err != nil {
+			return nil, fmt.Errorf("operation %s: %w", op.Name, err)
+		}
+		doc.Operations = append(doc.Operations, operation{
+			Name:     op.Name,
+			Action:   service.Namespace + "/" + op.Name,
+			Request:  "tns:" + request,
+			Response: "tns:" + response,
+		})
+	}
+	doc.Schemas = append(doc.Schemas, builder.schema)
+
+	if service.Fault != nil {
+		t := reflect.TypeOf(service.Fault)
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		name, err := elementName(t)
+		if err != nil {
+			return nil, fmt.Errorf("fault: %w", err)
+		}
+		if name.Space == "" || name.Space == service.Namespace {
+			return nil, fmt.Errorf("fault: element %s must have its own namespace", name.Local)
+		}
+		faults := newSchemaBuilder(name.Space, "fault", service.SimpleTypes)
+		local, err := faults.global(service.Fault)
+		if err != nil {
+			return nil, fmt.Errorf("fault: %w", err)
+		}
+		doc.Schemas = append(doc.Schemas, faults.schema)
+		doc.Fault = "fault:" + local
+	}
+
+	// Check the template with the document once, so Render only fails on writes
+	if _, err := doc.Render("http://localhost/"); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// BaseName is the name of the service without its Service suffix, which
+// names the port type, bindings and ports
+func (d *Document) BaseName() string {
+	return strings.TrimSuffix(d.Name, "Service")
+}
+
+// Render writes the document with the given service address
+func (d *Document) Render(address string) ([]byte, error) {
+	var out bytes.Buffer
+	err := documentTemplate.Execute(&out, struct {
+		*Document
+		Address string
+	}{d, address})
+	if err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
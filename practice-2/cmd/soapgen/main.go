@@ -0,0 +1,261 @@
+// Command soapgen generates the server side of the SOAP 1.1 bindings in a
+// WSDL document. For every port type it writes a Register function that
+// routes the operations of the port type, by SOAPAction or request element,
+// to an implementation of the interface gowsdl generates for it, using the
+// dispatch package to decode requests and encode responses and faults.
+// One-way operations, which have no output, are registered with
+// dispatch.RegisterOneWay.
+//
+// Usage:
+//
+//	soapgen -p currency -o currency_server_gen.go ./wsdl/currency.wsdl
+//
+// As with gowsdl, the file is written to a directory named after the package.
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// definitions holds the parts of a WSDL document the bindings are read from
+type definitions struct {
+	Messages  []message  `xml:"http://schemas.xmlsoap.org/wsdl/ message"`
+	PortTypes []portType `xml:"http://schemas.xmlsoap.org/wsdl/ portType"`
+	Bindings  []binding  `xml:"http://schemas.xmlsoap.org/wsdl/ binding"`
+}
+
+type message struct {
+	Name  string `xml:"name,attr"`
+	Parts []struct {
+		Name    string `xml:"name,attr"`
+		Element string `xml:"element,attr"`
+	} `xml:"http://schemas.xmlsoap.org/wsdl/ part"`
+}
+
+type portType struct {
+	Name       string `xml:"name,attr"`
+	Operations []struct {
+		Name   string `xml:"name,attr"`
+		Input  ioRef  `xml:"http://schemas.xmlsoap.org/wsdl/ input"`
+		Output ioRef  `xml:"http://schemas.xmlsoap.org/wsdl/ output"`
+	} `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+}
+
+type ioRef struct {
+	Message string `xml:"message,attr"`
+}
+
+type binding struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+	// SOAP is set for SOAP 1.1 bindings
+	SOAP *struct {
+		Style string `xml:"style,attr"`
+	} `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+	Operations []struct {
+		Name      string `xml:"name,attr"`
+		Operation struct {
+			SOAPAction string `xml:"soapAction,attr"`
+		} `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	} `xml:"http://schemas.xmlsoap.org/wsdl/ operation"`
+}
+
+// server is the generated code for one port type and its SOAP binding
+type server struct {
+	// Interface is the name gowsdl gives the port type's interface
+	Interface  string
+	PortType   string
+	Binding    string
+	Operations []serverOperation
+}
+
+type serverOperation struct {
+	Name string
+	// Method is the context-aware method of the interface
+	Method  string
+	Action  string
+	Request string
+	// Response is empty for one-way operations
+	Response string
+}
+
+var serverTemplate = template.Must(template.New("server").Parse(`// Code generated by soapgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import "{{.Dispatch}}"
+{{range .Servers}}
+// Register{{.Interface}} registers the operations of the {{.PortType}}
+// port type with a dispatcher, routed by the SOAPAction of the {{.Binding}}
+// binding or by their request element. Errors returned by the service are reported
+// as faults by the dispatcher's FaultFor.
+func Register{{.Interface}}(d *dispatch.Dispatcher, service {{.Interface}}) {
+{{- range .Operations}}
+{{- if .Response}}
+	// {{.Name}}: {{.Request}} -> {{.Response}}
+	dispatch.Register(d, {{printf "%q" .Action}}, service.{{.Method}})
+{{- else}}
+	// {{.Name}}: {{.Request}} (one-way)
+	dispatch.RegisterOneWay(d, {{printf "%q" .Action}}, service.{{.Method}})
+{{- end}}
+{{- end}}
+}
+{{end}}`))
+
+func main() {
+	pkg := flag.String("p", "", "package of the generated code")
+	output := flag.String("o", "server_gen.go", "file name of the generated code")
+	dispatchPath := flag.String("dispatch", "practice-2/dispatch", "import path of the dispatch package")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: soapgen -p package [-o file] [-dispatch path] file.wsdl\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *pkg == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	log.SetFlags(0)
+	log.SetPrefix("soapgen: ")
+
+	source := flag.Arg(0)
+	data, err := os.ReadFile(source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	servers, err := parse(data)
+	if err != nil {
+		log.Fatalf("%s: %v", source, err)
+	}
+
+	var out bytes.Buffer
+	err = serverTemplate.Execute(&out, struct {
+		Source   string
+		Package  string
+		Dispatch string
+		Servers  []server
+	}{filepath.Base(source), *pkg, *dispatchPath, servers})
+	if err != nil {
+		log.Fatal(err)
+	}
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatalf("formatting generated code: %v", err)
+	}
+
+	if err := os.MkdirAll(*pkg, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(*pkg, *output), code, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parse reads the servers of the SOAP 1.1 bindings of a WSDL document
+func parse(data []byte) ([]server, error) {
+	var defs definitions
+	if err := xml.Unmarshal(data, &defs); err != nil {
+		return nil, err
+	}
+
+	messages := make(map[string]message)
+	for _, m := range defs.Messages {
+		messages[m.Name] = m
+	}
+	portTypes := make(map[string]portType)
+	for _, p := range defs.PortTypes {
+		portTypes[p.Name] = p
+	}
+
+	var servers []server
+	for _, b := range defs.Bindings {
+		if b.SOAP == nil {
+			continue
+		}
+		if b.SOAP.Style != "" && b.SOAP.Style != "document" {
+			return nil, fmt.Errorf("binding %s: only document style is supported", b.Name)
+		}
+		pt, ok := portTypes[localName(b.Type)]
+		if !ok {
+			return nil, fmt.Errorf("binding %s: unknown port type %s", b.Name, b.Type)
+		}
+
+		actions := make(map[string]string)
+		for _, op := range b.Operations {
+			actions[op.Name] = op.Operation.SOAPAction
+		}
+
+		s := server{Interface: exported(pt.Name), PortType: pt.Name, Binding: b.Name}
+		for _, op := range pt.Operations {
+			action, ok := actions[op.Name]
+			if !ok {
+				return nil, fmt.Errorf("binding %s: operation %s is not bound", b.Name, op.Name)
+			}
+			request, err := element(messages, op.Input.Message)
+			if err != nil {
+				return nil, fmt.Errorf("operation %s: %w", op.Name, err)
+			}
+			var response string
+			if op.Output.Message != "" {
+				if response, err = element(messages, op.Output.Message); err != nil {
+					return nil, fmt.Errorf("operation %s: %w", op.Name, err)
+				}
+			}
+			method := exported(op.Name) + "Context"
+			if !token.IsIdentifier(method) {
+				return nil, fmt.Errorf("operation %s: name is not a Go identifier", op.Name)
+			}
+			s.Operations = append(s.Operations, serverOperation{
+				Name:     op.Name,
+				Method:   method,
+				Action:   action,
+				Request:  request,
+				Response: response,
+			})
+		}
+		servers = append(servers, s)
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no SOAP 1.1 bindings")
+	}
+	return servers, nil
+}
+
+// element returns the element of the single part of a document/literal message
+func element(messages map[string]message, ref string) (string, error) {
+	m, ok := messages[localName(ref)]
+	if !ok {
+		return "", fmt.Errorf("unknown message %s", ref)
+	}
+	if len(m.Parts) != 1 || m.Parts[0].Element == "" {
+		return "", fmt.Errorf("message %s must have a single element part", m.Name)
+	}
+	return m.Parts[0].Element, nil
+}
+
+// localName strips the prefix of a qualified name
+func localName(qname string) string {
+	if _, local, ok := strings.Cut(qname, ":"); ok {
+		return local
+	}
+	return qname
+}
+
+// exported returns a name with its first letter in upper case, as gowsdl
+// names the interfaces and methods it generates
+func exported(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
@@ -24,29 +24,268 @@ type NCName string
 type ConvertCurrencyRequest struct {
 	XMLName xml.Name `xml:"http://practice-2/soap ConvertCurrencyRequest"`
 
-	Amount float64 `xml:"amount,omitempty" json:"amount,omitempty"`
+	Amount Decimal `xml:"amount,omitempty" json:"amount,omitempty"`
 
 	FromCurrency string `xml:"fromCurrency,omitempty" json:"fromCurrency,omitempty"`
 
 	ToCurrency string `xml:"toCurrency,omitempty" json:"toCurrency,omitempty"`
+
+	ValueDate soap.XSDDate `xml:"valueDate,omitempty" json:"valueDate,omitempty"`
 }
 
 type ConvertCurrencyResponse struct {
 	XMLName xml.Name `xml:"http://practice-2/soap ConvertCurrencyResponse"`
 
-	ConvertedAmount float64 `xml:"convertedAmount,omitempty" json:"convertedAmount,omitempty"`
+	ConvertedAmount Decimal `xml:"convertedAmount,omitempty" json:"convertedAmount,omitempty"`
+
+	FromCurrency string `xml:"fromCurrency,omitempty" json:"fromCurrency,omitempty"`
+
+	ToCurrency string `xml:"toCurrency,omitempty" json:"toCurrency,omitempty"`
+
+	Rate Decimal `xml:"rate,omitempty" json:"rate,omitempty"`
+
+	Path *ConversionPath `xml:"path,omitempty" json:"path,omitempty"`
+
+	ValueDate soap.XSDDate `xml:"valueDate,omitempty" json:"valueDate,omitempty"`
+
+	RateDate soap.XSDDate `xml:"rateDate,omitempty" json:"rateDate,omitempty"`
+
+	MidRate Decimal `xml:"midRate,omitempty" json:"midRate,omitempty"`
+
+	AppliedRate Decimal `xml:"appliedRate,omitempty" json:"appliedRate,omitempty"`
+
+	Fee Decimal `xml:"fee,omitempty" json:"fee,omitempty"`
+
+	NetAmount Decimal `xml:"netAmount,omitempty" json:"netAmount,omitempty"`
+}
+
+type SubmitConversionRequest struct {
+	XMLName xml.Name `xml:"http://practice-2/soap SubmitConversionRequest"`
+
+	Amount Decimal `xml:"amount,omitempty" json:"amount,omitempty"`
 
 	FromCurrency string `xml:"fromCurrency,omitempty" json:"fromCurrency,omitempty"`
 
 	ToCurrency string `xml:"toCurrency,omitempty" json:"toCurrency,omitempty"`
 
-	Rate float64 `xml:"rate,omitempty" json:"rate,omitempty"`
+	ValueDate soap.XSDDate `xml:"valueDate,omitempty" json:"valueDate,omitempty"`
+
+	CallbackURL AnyURI `xml:"callbackURL,omitempty" json:"callbackURL,omitempty"`
+}
+
+type ListCurrenciesRequest struct {
+	XMLName xml.Name `xml:"http://practice-2/soap ListCurrenciesRequest"`
+
+	IncludeInactive bool `xml:"includeInactive,omitempty" json:"includeInactive,omitempty"`
+}
+
+type ListCurrenciesResponse struct {
+	XMLName xml.Name `xml:"http://practice-2/soap ListCurrenciesResponse"`
+
+	Currency []*CurrencyInfo `xml:"currency,omitempty" json:"currency,omitempty"`
+}
+
+type ConvertCurrencyBatchRequest struct {
+	XMLName xml.Name `xml:"http://practice-2/soap ConvertCurrencyBatchRequest"`
+
+	Item []*ConversionItem `xml:"item,omitempty" json:"item,omitempty"`
+}
+
+type ConvertCurrencyBatchResponse struct {
+	XMLName xml.Name `xml:"http://practice-2/soap ConvertCurrencyBatchResponse"`
+
+	Result []*ConversionItemResult `xml:"result,omitempty" json:"result,omitempty"`
+}
+
+type GetQuoteRequest struct {
+	XMLName xml.Name `xml:"http://practice-2/soap GetQuoteRequest"`
+
+	FromCurrency string `xml:"fromCurrency,omitempty" json:"fromCurrency,omitempty"`
+
+	ToCurrency string `xml:"toCurrency,omitempty" json:"toCurrency,omitempty"`
+}
+
+type GetQuoteResponse struct {
+	XMLName xml.Name `xml:"http://practice-2/soap GetQuoteResponse"`
+
+	QuoteId string `xml:"quoteId,omitempty" json:"quoteId,omitempty"`
+
+	FromCurrency string `xml:"fromCurrency,omitempty" json:"fromCurrency,omitempty"`
+
+	ToCurrency string `xml:"toCurrency,omitempty" json:"toCurrency,omitempty"`
+
+	Rate Decimal `xml:"rate,omitempty" json:"rate,omitempty"`
+
+	Path *ConversionPath `xml:"path,omitempty" json:"path,omitempty"`
+
+	RateDate soap.XSDDate `xml:"rateDate,omitempty" json:"rateDate,omitempty"`
+
+	ExpiresAt soap.XSDDateTime `xml:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+
+	MidRate Decimal `xml:"midRate,omitempty" json:"midRate,omitempty"`
+
+	AppliedRate Decimal `xml:"appliedRate,omitempty" json:"appliedRate,omitempty"`
+}
+
+type ConvertWithQuoteRequest struct {
+	XMLName xml.Name `xml:"http://practice-2/soap ConvertWithQuoteRequest"`
+
+	QuoteId string `xml:"quoteId,omitempty" json:"quoteId,omitempty"`
+
+	Amount Decimal `xml:"amount,omitempty" json:"amount,omitempty"`
+}
+
+type ConvertWithQuoteResponse struct {
+	XMLName xml.Name `xml:"http://practice-2/soap ConvertWithQuoteResponse"`
+
+	QuoteId string `xml:"quoteId,omitempty" json:"quoteId,omitempty"`
+
+	ConvertedAmount Decimal `xml:"convertedAmount,omitempty" json:"convertedAmount,omitempty"`
+
+	FromCurrency string `xml:"fromCurrency,omitempty" json:"fromCurrency,omitempty"`
+
+	ToCurrency string `xml:"toCurrency,omitempty" json:"toCurrency,omitempty"`
+
+	Rate Decimal `xml:"rate,omitempty" json:"rate,omitempty"`
+
+	Path *ConversionPath `xml:"path,omitempty" json:"path,omitempty"`
+
+	RateDate soap.XSDDate `xml:"rateDate,omitempty" json:"rateDate,omitempty"`
+
+	MidRate Decimal `xml:"midRate,omitempty" json:"midRate,omitempty"`
+
+	AppliedRate Decimal `xml:"appliedRate,omitempty" json:"appliedRate,omitempty"`
+
+	Fee Decimal `xml:"fee,omitempty" json:"fee,omitempty"`
+
+	NetAmount Decimal `xml:"netAmount,omitempty" json:"netAmount,omitempty"`
+}
+
+type ConversionItem struct {
+	Id string `xml:"id,omitempty" json:"id,omitempty"`
+
+	Amount Decimal `xml:"amount,omitempty" json:"amount,omitempty"`
+
+	FromCurrency string `xml:"fromCurrency,omitempty" json:"fromCurrency,omitempty"`
+
+	ToCurrency string `xml:"toCurrency,omitempty" json:"toCurrency,omitempty"`
+
+	ValueDate soap.XSDDate `xml:"valueDate,omitempty" json:"valueDate,omitempty"`
+}
+
+type ConversionItemResult struct {
+	Id string `xml:"id,omitempty" json:"id,omitempty"`
+
+	Conversion *ConversionResult `xml:"conversion,omitempty" json:"conversion,omitempty"`
+
+	Fault *ItemFault `xml:"fault,omitempty" json:"fault,omitempty"`
+}
+
+type ConversionResult struct {
+	ConvertedAmount Decimal `xml:"convertedAmount,omitempty" json:"convertedAmount,omitempty"`
+
+	FromCurrency string `xml:"fromCurrency,omitempty" json:"fromCurrency,omitempty"`
+
+	ToCurrency string `xml:"toCurrency,omitempty" json:"toCurrency,omitempty"`
+
+	Rate Decimal `xml:"rate,omitempty" json:"rate,omitempty"`
+
+	Path *ConversionPath `xml:"path,omitempty" json:"path,omitempty"`
+
+	ValueDate soap.XSDDate `xml:"valueDate,omitempty" json:"valueDate,omitempty"`
+
+	RateDate soap.XSDDate `xml:"rateDate,omitempty" json:"rateDate,omitempty"`
+
+	MidRate Decimal `xml:"midRate,omitempty" json:"midRate,omitempty"`
+
+	AppliedRate Decimal `xml:"appliedRate,omitempty" json:"appliedRate,omitempty"`
+
+	Fee Decimal `xml:"fee,omitempty" json:"fee,omitempty"`
+
+	NetAmount Decimal `xml:"netAmount,omitempty" json:"netAmount,omitempty"`
+}
+
+type ItemFault struct {
+	Faultcode string `xml:"faultcode,omitempty" json:"faultcode,omitempty"`
+
+	Faultstring string `xml:"faultstring,omitempty" json:"faultstring,omitempty"`
+
+	ErrorCode string `xml:"errorCode,omitempty" json:"errorCode,omitempty"`
+
+	Detail string `xml:"detail,omitempty" json:"detail,omitempty"`
+}
+
+type ConversionPath struct {
+	Currency []string `xml:"currency,omitempty" json:"currency,omitempty"`
+}
+
+type CurrencyInfo struct {
+	Code string `xml:"code,omitempty" json:"code,omitempty"`
+
+	NumericCode string `xml:"numericCode,omitempty" json:"numericCode,omitempty"`
+
+	Name string `xml:"name,omitempty" json:"name,omitempty"`
+
+	MinorUnits int32 `xml:"minorUnits" json:"minorUnits"`
+
+	Active bool `xml:"active" json:"active"`
+}
+
+type Error struct {
+	XMLName xml.Name `xml:"http://practice/soap/fault error"`
+
+	Code string `xml:"code,omitempty" json:"code,omitempty"`
+
+	Message string `xml:"message,omitempty" json:"message,omitempty"`
+
+	Element string `xml:"element,omitempty" json:"element,omitempty"`
 }
 
 type CurrencyConversionPortType interface {
+
+	// Error can be either of the following types:
+	//
+	//   - ServiceFault
+
 	ConvertCurrency(request *ConvertCurrencyRequest) (*ConvertCurrencyResponse, error)
 
 	ConvertCurrencyContext(ctx context.Context, request *ConvertCurrencyRequest) (*ConvertCurrencyResponse, error)
+
+	// Error can be either of the following types:
+	//
+	//   - ServiceFault
+
+	ListCurrencies(request *ListCurrenciesRequest) (*ListCurrenciesResponse, error)
+
+	ListCurrenciesContext(ctx context.Context, request *ListCurrenciesRequest) (*ListCurrenciesResponse, error)
+
+	// Error can be either of the following types:
+	//
+	//   - ServiceFault
+
+	ConvertCurrencyBatch(request *ConvertCurrencyBatchRequest) (*ConvertCurrencyBatchResponse, error)
+
+	ConvertCurrencyBatchContext(ctx context.Context, request *ConvertCurrencyBatchRequest) (*ConvertCurrencyBatchResponse, error)
+
+	// Error can be either of the following types:
+	//
+	//   - ServiceFault
+
+	GetQuote(request *GetQuoteRequest) (*GetQuoteResponse, error)
+
+	GetQuoteContext(ctx context.Context, request *GetQuoteRequest) (*GetQuoteResponse, error)
+
+	// Error can be either of the following types:
+	//
+	//   - ServiceFault
+
+	ConvertWithQuote(request *ConvertWithQuoteRequest) (*ConvertWithQuoteResponse, error)
+
+	ConvertWithQuoteContext(ctx context.Context, request *ConvertWithQuoteRequest) (*ConvertWithQuoteResponse, error)
+
+	SubmitConversion(request *SubmitConversionRequest) error
+
+	SubmitConversionContext(ctx context.Context, request *SubmitConversionRequest) error
 }
 
 type currencyConversionPortType struct {
@@ -75,3 +314,88 @@ func (service *currencyConversionPortType) ConvertCurrency(request *ConvertCurre
 		request,
 	)
 }
+
+func (service *currencyConversionPortType) ListCurrenciesContext(ctx context.Context, request *ListCurrenciesRequest) (*ListCurrenciesResponse, error) {
+	response := new(ListCurrenciesResponse)
+	err := service.client.CallContext(ctx, "http://practice-2/soap/ListCurrencies", request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (service *currencyConversionPortType) ListCurrencies(request *ListCurrenciesRequest) (*ListCurrenciesResponse, error) {
+	return service.ListCurrenciesContext(
+		context.Background(),
+		request,
+	)
+}
+
+func (service *currencyConversionPortType) ConvertCurrencyBatchContext(ctx context.Context, request *ConvertCurrencyBatchRequest) (*ConvertCurrencyBatchResponse, error) {
+	response := new(ConvertCurrencyBatchResponse)
+	err := service.client.CallContext(ctx, "http://practice-2/soap/ConvertCurrencyBatch", request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (service *currencyConversionPortType) ConvertCurrencyBatch(request *ConvertCurrencyBatchRequest) (*ConvertCurrencyBatchResponse, error) {
+	return service.ConvertCurrencyBatchContext(
+		context.Background(),
+		request,
+	)
+}
+
+func (service *currencyConversionPortType) GetQuoteContext(ctx context.Context, request *GetQuoteRequest) (*GetQuoteResponse, error) {
+	response := new(GetQuoteResponse)
+	err := service.client.CallContext(ctx, "http://practice-2/soap/GetQuote", request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (service *currencyConversionPortType) GetQuote(request *GetQuoteRequest) (*GetQuoteResponse, error) {
+	return service.GetQuoteContext(
+		context.Background(),
+		request,
+	)
+}
+
+func (service *currencyConversionPortType) ConvertWithQuoteContext(ctx context.Context, request *ConvertWithQuoteRequest) (*ConvertWithQuoteResponse, error) {
+	response := new(ConvertWithQuoteResponse)
+	err := service.client.CallContext(ctx, "http://practice-2/soap/ConvertWithQuote", request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (service *currencyConversionPortType) ConvertWithQuote(request *ConvertWithQuoteRequest) (*ConvertWithQuoteResponse, error) {
+	return service.ConvertWithQuoteContext(
+		context.Background(),
+		request,
+	)
+}
+
+func (service *currencyConversionPortType) SubmitConversionContext(ctx context.Context, request *SubmitConversionRequest) error {
+
+	err := service.client.CallContext(ctx, "http://practice-2/soap/SubmitConversion", request, struct{}{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (service *currencyConversionPortType) SubmitConversion(request *SubmitConversionRequest) error {
+	return service.SubmitConversionContext(
+		context.Background(),
+		request,
+	)
+}
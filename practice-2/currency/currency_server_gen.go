@@ -0,0 +1,24 @@
+// Code generated by soapgen from currency.wsdl. DO NOT EDIT.
+
+package currency
+
+import "practice-2/dispatch"
+
+// RegisterCurrencyConversionPortType registers the operations of the CurrencyConversionPortType
+// port type with a dispatcher, routed by the SOAPAction of the CurrencyConversionBinding
+// binding or by their request element. Errors returned by the service are reported
+// as faults by the dispatcher's FaultFor.
+func RegisterCurrencyConversionPortType(d *dispatch.Dispatcher, service CurrencyConversionPortType) {
+	// ConvertCurrency: tns:ConvertCurrencyRequest -> tns:ConvertCurrencyResponse
+	dispatch.Register(d, "http://practice-2/soap/ConvertCurrency", service.ConvertCurrencyContext)
+	// ListCurrencies: tns:ListCurrenciesRequest -> tns:ListCurrenciesResponse
+	dispatch.Register(d, "http://practice-2/soap/ListCurrencies", service.ListCurrenciesContext)
+	// ConvertCurrencyBatch: tns:ConvertCurrencyBatchRequest -> tns:ConvertCurrencyBatchResponse
+	dispatch.Register(d, "http://practice-2/soap/ConvertCurrencyBatch", service.ConvertCurrencyBatchContext)
+	// GetQuote: tns:GetQuoteRequest -> tns:GetQuoteResponse
+	dispatch.Register(d, "http://practice-2/soap/GetQuote", service.GetQuoteContext)
+	// ConvertWithQuote: tns:ConvertWithQuoteRequest -> tns:ConvertWithQuoteResponse
+	dispatch.Register(d, "http://practice-2/soap/ConvertWithQuote", service.ConvertWithQuoteContext)
+	// SubmitConversion: tns:SubmitConversionRequest (one-way)
+	dispatch.RegisterOneWay(d, "http://practice-2/soap/SubmitConversion", service.SubmitConversionContext)
+}
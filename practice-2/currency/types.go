@@ -0,0 +1,7 @@
+package currency
+
+import "money"
+
+// Decimal is the exact decimal type used for xsd:decimal values.
+// wsdl_gen.sh maps the float64 fields gowsdl generates for xsd:decimal to it.
+type Decimal = money.Decimal
@@ -0,0 +1,571 @@
+// Package dispatch routes SOAP requests to typed operation handlers, either
+// by their action, the wsa:Action header or the SOAPAction HTTP header, or by
+// the qualified name of the first element in the SOAP Body.
+package dispatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"practice-2/schema"
+	"practice-2/wsaddressing"
+	"soapfault"
+	"soapheader"
+	"wssecurity"
+	"xmllimit"
+)
+
+// operation is a registered handler for one request element
+type operation struct {
+	// name is the operation name, the request element without its Request suffix
+	name   string
+	action string
+	// replyAction is the wsa:Action of the response
+	replyAction string
+	element     xml.Name
+	// oneWay operations have no response; requests are answered with 202 Accepted
+	oneWay bool
+	// timeout overrides the default timeout of the dispatcher when set
+	timeout time.Duration
+	// decode reads the request element into a new request value
+	decode func(decoder *xml.Decoder) (interface{}, error)
+	// call invokes the typed handler with a decoded request
+	call func(ctx context.Context, request interface{}) (interface{}, error)
+}
+
+// Dispatcher is an http.Handler serving the operations registered with Register
+type Dispatcher struct {
+	// namespace is the target namespace of the request elements
+	namespace string
+	// schema checks the structure of request elements
+	schema     *schema.Schema
+	actions    map[string]*operation
+	elements   map[xml.Name]*operation
+	operations []*operation
+
+	// ValidateResponses checks every response against the schema before it
+	// is sent and answers with a Server fault instead if it is invalid.
+	// It is meant for debugging, as it parses each response again.
+	ValidateResponses bool
+
+	// Timeout limits how long each operation may run unless SetTimeout
+	// gives it its own limit; zero means no limit. Handlers see it as the
+	// deadline of their context and an operation that runs out of time is
+	// answered with a Server fault with the TIMEOUT error code.
+	Timeout time.Duration
+
+	// Limits are the size and XML limits of requests. Requests that exceed
+	// them are answered with a Client fault as soon as the limit is reached.
+	Limits xmllimit.Limits
+
+	// Headers processes the entries of the SOAP Header before a request is
+	// routed. Handlers registered with it add values to the context of the
+	// operation handlers and entries to the header of the response. It
+	// understands the WS-Addressing headers, see wsaddressing.FromContext.
+	Headers *soapheader.Registry
+	// security authenticates requests when set, see RequireAuthentication
+	security *wssecurity.Verifier
+
+	// FaultFor maps the errors returned by operation handlers to SOAP faults.
+	// Without it, errors are reported with the *soapfault.Error in their
+	// chain, or as Server faults if they have none.
+	FaultFor func(err error) *soapfault.Error
+}
+
+// New creates a dispatcher for operations whose request elements are declared
+// in the given schema and its target namespace
+func New(s *schema.Schema) *Dispatcher {
+	d := &Dispatcher{
+		namespace: s.Namespace,
+		schema:    s,
+		Limits:    xmllimit.Default,
+		Headers:   soapheader.NewRegistry(),
+		actions:   make(map[string]*operation),
+		elements:  make(map[xml.Name]*operation),
+	}
+	wsaddressing.Register(d.Headers)
+	return d
+}
+
+// Register adds an operation to the dispatcher. The request element is taken
+// from the XMLName field of Req, as in the types generated by gowsdl, e.g.
+//
+//	dispatch.Register(d, "http://practice-2/soap/ConvertCurrency", service.ConvertCurrencyContext)
+//
+// Responses carry the wsa:Action of the request with a Response suffix, or
+// the namespace and the response element for operations without an action.
+//
+// Register panics if Req has no XMLName or the action or element is already
+// registered.
+func Register[Req, Resp any](d *Dispatcher, action string, fn func(context.Context, *Req) (*Resp, error)) {
+	op := newOperation[Req](d, action)
+	op.call = func(ctx context.Context, request interface{}) (interface{}, error) {
+		return fn(ctx, request.(*Req))
+	}
+	d.add(op)
+}
+
+// RegisterOneWay adds an operation without a response to the dispatcher.
+// Requests the handler accepts are answered with 202 Accepted and an empty
+// Body; the handler is expected to hand longer work to a queue rather than
+// hold the connection open. It panics as Register does.
+func RegisterOneWay[Req any](d *Dispatcher, action string, fn func(context.Context, *Req) error) {
+	op := newOperation[Req](d, action)
+	op.oneWay = true
+	op.call = func(ctx context.Context, request interface{}) (interface{}, error) {
+		return nil, fn(ctx, request.(*Req))
+	}
+	d.add(op)
+}
+
+// newOperation checks the request element of Req and the action of a new
+// operation and returns the operation without its handler
+func newOperation[Req any](d *Dispatcher, action string) *operation {
+	element, err := elementName(reflect.TypeOf((*Req)(nil)).Elem())
+	if err != nil {
+		panic(fmt.Sprintf("dispatch: %v", err))
+	}
+	if element.Space == "" {
+		element.Space = d.namespace
+	}
+	if _, ok := d.schema.Elements[element.Local]; !ok || element.Space != d.namespace {
+		panic(fmt.Sprintf("dispatch: element %s is not declared in the schema", element.Local))
+	}
+	if _, dup := d.elements[element]; dup {
+		panic(fmt.Sprintf("dispatch: multiple registrations for element %s", element.Local))
+	}
+	if _, dup := d.actions[action]; dup && action != "" {
+		panic(fmt.Sprintf("dispatch: multiple registrations for SOAPAction %s", action))
+	}
+
+	name := strings.TrimSuffix(element.Local, "Request")
+	replyAction := action + "Response"
+	if action == "" {
+		replyAction = d.namespace + "/" + name + "Response"
+	}
+	return &operation{
+		name:        name,
+		action:      action,
+		replyAction: replyAction,
+		element:     element,
+		decode: func(decoder *xml.Decoder) (interface{}, error) {
+			request := new(Req)
+			if err := decoder.Decode(request); err != nil {
+				return nil, err
+			}
+			return request, nil
+		},
+	}
+}
+
+// add makes an operation available by its action and request element
+func (d *Dispatcher) add(op *operation) {
+	d.elements[op.element] = op
+	if op.action != "" {
+		d.actions[op.action] = op
+	}
+	d.operations = append(d.operations, op)
+}
+
+// RequireAuthentication authenticates every request by its WS-Security
+// header before it is routed. The username is passed to the handlers in
+// their context, see wssecurity.UsernameFromContext.
+func (d *Dispatcher) RequireAuthentication(verifier *wssecurity.Verifier) {
+	d.Headers.Handle(wssecurity.Name, wssecurity.HandleHeader)
+	d.security = verifier
+}
+
+// SetTimeout limits how long the named operation, e.g. ConvertCurrencyBatch,
+// may run, overriding the default Timeout
+func (d *Dispatcher) SetTimeout(name string, timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("timeout of operation %s must be positive", name)
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			op.timeout = timeout
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown operation %s", name)
+}
+
+// ServeHTTP decodes the SOAP envelope, routes the request to its operation
+// and writes the response or a SOAP fault
+func (d *Dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 1. Basic request validation
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 2. Parse the request body as it is read, in a single pass that stops
+	// at the first limit the request exceeds
+	ctx := r.Context()
+	defer r.Body.Close()
+	decoder := d.Limits.NewDecoder(xml.NewDecoder(d.Limits.Body(w, r)))
+
+	// 3. Find the request element inside the envelope, reading the header on the way
+	start, entries, err := requestElement(decoder)
+	if err != nil {
+		var mismatch *versionMismatchError
+		if errors.As(err, &mismatch) {
+			sendFault(ctx, w, soapfault.New(soapfault.VersionMismatch, soapfault.UnsupportedEnvelope, "Unsupported SOAP envelope", err))
+			return
+		}
+		sendFault(ctx, w, parseFault("Failed to parse request", err))
+		return
+	}
+
+	// 4. Process the header entries and authenticate the client
+	ctx, err = d.Headers.Process(ctx, EnvelopeNamespace, entries)
+	if err != nil {
+		sendFault(ctx, w, soapfault.From(err))
+		return
+	}
+	if d.security != nil {
+		if ctx, err = d.security.Authenticate(ctx); err != nil {
+			log.Printf("dispatch: authentication failed: %v", err)
+			sendFault(ctx, w, soapfault.From(err))
+			return
+		}
+	}
+
+	// 5. Route by wsa:Action or SOAPAction and request element
+	action, err := wsaddressing.RequestAction(ctx, strings.Trim(r.Header.Get("SOAPAction"), `"`))
+	if err != nil {
+		sendFault(ctx, w, soapfault.From(err))
+		return
+	}
+	op, err := d.route(action, start.Name)
+	if err != nil {
+		if _, addressed := wsaddressing.FromContext(ctx); addressed && d.actions[action] == nil {
+			sendFault(ctx, w, wsaddressing.ActionNotSupported(action))
+			return
+		}
+		sendFault(ctx, w, soapfault.New(soapfault.Client, soapfault.UnknownOperation, "Unknown operation", err))
+		return
+	}
+	// The reply of a request-response operation relates to the wsa:MessageID
+	// of the request and goes back in the HTTP response; only one-way
+	// operations may do without the one and send their reply elsewhere
+	if !op.oneWay {
+		err := wsaddressing.RequireMessageID(ctx)
+		if err == nil {
+			err = wsaddressing.RequireAnonymous(ctx)
+		}
+		if err != nil {
+			sendFault(ctx, w, soapfault.From(err))
+			return
+		}
+	}
+
+	// 6. Check the request element against the schema; it must be complete,
+	// as missing elements would otherwise decode to zero values. Its tokens
+	// are recorded on the way for the typed request, so the element is the
+	// only part of the body held beyond the parser's buffer.
+	element := &recorder{Decoder: decoder, tokens: []xml.Token{soapheader.WithoutNamespaceDeclarations(start)}}
+	if err := d.schema.Validate(element, start); err != nil {
+		var invalid *schema.ValidationError
+		if errors.As(err, &invalid) {
+			fault := soapfault.New(soapfault.Client, soapfault.InvalidRequest, "Invalid request", err)
+			fault.Element = invalid.Path
+			sendFault(ctx, w, fault)
+			return
+		}
+		sendFault(ctx, w, parseFault("Failed to parse request", err))
+		return
+	}
+	// Nothing may follow the request element, which is checked before the
+	// request is processed, as one-way requests are answered right away
+	if err := envelopeEnd(decoder); err != nil {
+		sendFault(ctx, w, parseFault("Failed to parse request", err))
+		return
+	}
+
+	// 7. Decode the typed request from the recorded tokens and process it
+	request, err := op.decode(xml.NewTokenDecoder(&replay{tokens: element.tokens}))
+	if err != nil {
+		sendFault(ctx, w, parseFault("Failed to parse request", err))
+		return
+	}
+	if timeout := d.timeout(op); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	response, err := op.call(ctx, request)
+	if err != nil {
+		sendFault(ctx, w, d.faultFor(err))
+		return
+	}
+	if op.oneWay {
+		sendAccepted(ctx, w)
+		return
+	}
+
+	// 8. Send response, checking it against the schema in debug mode
+	output, err := encodeResponse(ctx, op.replyAction, response)
+	if err != nil {
+		sendFault(ctx, w, soapfault.New(soapfault.Server, soapfault.InternalError, "Failed to encode response", err))
+		return
+	}
+	if d.ValidateResponses {
+		if err := d.validateResponse(output); err != nil {
+			log.Printf("dispatch: invalid %s response: %v", op.element.Local, err)
+			sendFault(ctx, w, soapfault.New(soapfault.Server, soapfault.InternalError, "Invalid response", err))
+			return
+		}
+	}
+	sendResponse(w, output)
+}
+
+// timeout returns the time limit of an operation
+func (d *Dispatcher) timeout(op *operation) time.Duration {
+	if op.timeout > 0 {
+		return op.timeout
+	}
+	return d.Timeout
+}
+
+// faultFor returns the SOAP fault for an error of an operation handler
+func (d *Dispatcher) faultFor(err error) *soapfault.Error {
+	if d.FaultFor != nil {
+		return d.FaultFor(err)
+	}
+	return soapfault.From(err)
+}
+
+// parseFault returns the fault for a request that cannot be read or parsed:
+// the fault of the limit it exceeds, or a MALFORMED_REQUEST fault
+func parseFault(faultString string, err error) *soapfault.Error {
+	var fault *soapfault.Error
+	if errors.As(err, &fault) {
+		return fault
+	}
+	return soapfault.New(soapfault.Client, soapfault.MalformedRequest, faultString, err)
+}
+
+// validateResponse checks the body element of an encoded response against the schema
+func (d *Dispatcher) validateResponse(output []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(output))
+	start, _, err := requestElement(decoder)
+	if err != nil {
+		return err
+	}
+	if err := d.schema.Validate(decoder, start); err != nil {
+		return err
+	}
+	return envelopeEnd(decoder)
+}
+
+// route picks the operation for a request. An action, when present, must
+// name a registered operation whose request element matches the body.
+func (d *Dispatcher) route(action string, element xml.Name) (*operation, error) {
+	if element.Space != d.namespace {
+		return nil, fmt.Errorf("request element %s must be in namespace %q", element.Local, d.namespace)
+	}
+	byElement := d.elements[element]
+
+	if action == "" {
+		if byElement == nil {
+			return nil, fmt.Errorf("expected %s", d.expected())
+		}
+		return byElement, nil
+	}
+
+	byAction, ok := d.actions[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown SOAPAction %q", action)
+	}
+	if byElement != byAction {
+		return nil, fmt.Errorf("action %q expects %s, got %s", action, byAction.element.Local, element.Local)
+	}
+	return byAction, nil
+}
+
+// expected lists the registered request elements for error messages
+func (d *Dispatcher) expected() string {
+	names := make([]string, len(d.operations))
+	for i, op := range d.operations {
+		names[i] = op.element.Local
+	}
+	switch len(names) {
+	case 0:
+		return "no request"
+	case 1:
+		return names[0]
+	default:
+		return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+	}
+}
+
+// versionMismatchError is returned for envelopes outside the SOAP 1.1 namespace
+type versionMismatchError struct {
+	namespace string
+}
+
+func (e *versionMismatchError) Error() string {
+	return fmt.Sprintf("expected a SOAP 1.1 Envelope in namespace %s, got namespace %q", EnvelopeNamespace, e.namespace)
+}
+
+// requestElement advances the decoder to the first element in the SOAP Body
+// and returns it with the entries of the SOAP Header, if any. It reads
+// responses as well as requests; envelopeEnd reads the rest of them.
+func requestElement(decoder xml.TokenReader) (xml.StartElement, []*soapheader.Entry, error) {
+	envelope, err := nextElement(decoder)
+	if err != nil {
+		return xml.StartElement{}, nil, err
+	}
+	if envelope.Name.Local != "Envelope" {
+		return xml.StartElement{}, nil, fmt.Errorf("expected Envelope, got %s", envelope.Name.Local)
+	}
+	if envelope.Name.Space != EnvelopeNamespace {
+		return xml.StartElement{}, nil, &versionMismatchError{namespace: envelope.Name.Space}
+	}
+
+	var entries []*soapheader.Entry
+	header := false
+	for {
+		element, err := nextElement(decoder)
+		if err != nil {
+			return xml.StartElement{}, nil, err
+		}
+		if element.Name.Space != EnvelopeNamespace {
+			return xml.StartElement{}, nil, fmt.Errorf("element %s must be in namespace %s", element.Name.Local, EnvelopeNamespace)
+		}
+		switch element.Name.Local {
+		case "Header":
+			if header {
+				return xml.StartElement{}, nil, fmt.Errorf("repeated Header")
+			}
+			header = true
+			entries, err = soapheader.ReadEntries(decoder)
+			if err != nil {
+				return xml.StartElement{}, nil, err
+			}
+		case "Body":
+			request, err := nextElement(decoder)
+			if errors.Is(err, errEndElement) {
+				return xml.StartElement{}, nil, fmt.Errorf("empty Body")
+			}
+			return request, entries, err
+		default:
+			return xml.StartElement{}, nil, fmt.Errorf("expected Header or Body, got %s", element.Name.Local)
+		}
+	}
+}
+
+// envelopeEnd reads the rest of an envelope whose request element has been
+// read: the ends of the Body and the Envelope, followed by nothing but
+// comments and white space. Anything else, such as a second element in the
+// Body or a Header after it, is an error rather than being ignored.
+func envelopeEnd(decoder xml.TokenReader) error {
+	for _, parent := range []string{"Body", "Envelope"} {
+		element, err := nextElement(decoder)
+		if err == nil {
+			return fmt.Errorf("unexpected element %s at the end of the %s", element.Name.Local, parent)
+		}
+		if !errors.Is(err, errEndElement) {
+			return err
+		}
+	}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.Comment:
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return errors.New("unexpected text after the Envelope")
+			}
+		default:
+			return errors.New("unexpected content after the Envelope")
+		}
+	}
+}
+
+// errEndElement is returned by nextElement when the current element ends first
+var errEndElement = errors.New("unexpected end element")
+
+// nextElement returns the next start element at the current level
+func nextElement(decoder xml.TokenReader) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return xml.StartElement{}, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			return xml.StartElement{}, errEndElement
+		}
+	}
+}
+
+// recorder copies the tokens read through it
+type recorder struct {
+	schema.Decoder
+	tokens []xml.Token
+}
+
+func (r *recorder) Token() (xml.Token, error) {
+	token, err := r.Decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if start, ok := token.(xml.StartElement); ok {
+		r.tokens = append(r.tokens, soapheader.WithoutNamespaceDeclarations(start))
+	} else {
+		r.tokens = append(r.tokens, xml.CopyToken(token))
+	}
+	return token, nil
+}
+
+// replay returns recorded tokens in order
+type replay struct {
+	tokens []xml.Token
+}
+
+func (r *replay) Token() (xml.Token, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	token := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return token, nil
+}
+
+// elementName reads the request element from the XMLName tag of a struct type
+func elementName(t reflect.Type) (xml.Name, error) {
+	if t.Kind() == reflect.Struct {
+		if field, ok := t.FieldByName("XMLName"); ok && field.Type == reflect.TypeOf(xml.Name{}) {
+			tag, _, _ := strings.Cut(field.Tag.Get("xml"), ",")
+			if space, local, ok := strings.Cut(tag, " "); ok {
+				return xml.Name{Space: space, Local: local}, nil
+			}
+			if tag != "" {
+				return xml.Name{Local: tag}, nil
+			}
+		}
+	}
+	return xml.Name{}, fmt.Errorf("%s has no XMLName tag naming its request element", t)
+}
@@ -0,0 +1,108 @@
+package dispatch
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+
+	"practice-2/wsaddressing"
+	"soapfault"
+	"soapheader"
+)
+
+// EnvelopeNamespace is the SOAP 1.1 envelope namespace
+const EnvelopeNamespace = soapfault.SOAP11Namespace
+
+// Envelope is the root element for SOAP requests/responses
+type Envelope struct {
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	Header  *Header  `xml:",omitempty"`
+	Body    Body
+}
+
+// Header carries the entries of the response header
+type Header struct {
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Header"`
+	Entries []interface{}
+}
+
+// responseHeader returns the header of the response to the request of ctx,
+// or nil if it has neither WS-Addressing headers nor entries added by the
+// handlers of its header entries. action is the wsa:Action of the response.
+func responseHeader(ctx context.Context, action string) *Header {
+	entries := append(wsaddressing.ReplyHeaders(ctx, action), soapheader.ResponseHeaders(ctx)...)
+	if len(entries) == 0 {
+		return nil
+	}
+	return &Header{Entries: entries}
+}
+
+// Body contains the actual SOAP message
+type Body struct {
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+	Content interface{}
+	Fault   *soapfault.SOAP11Fault `xml:",omitempty"`
+}
+
+// encodeResponse marshals a successful SOAP response with the given wsa:Action
+func encodeResponse(ctx context.Context, action string, content interface{}) ([]byte, error) {
+	responseEnvelope := Envelope{
+		Header: responseHeader(ctx, action),
+		Body: Body{
+			Content: content,
+		},
+	}
+
+	output, err := xml.MarshalIndent(responseEnvelope, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(xml.Header + string(output)), nil
+}
+
+// sendResponse sends an encoded SOAP response
+func sendResponse(w http.ResponseWriter, output []byte) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write(output)
+}
+
+// sendAccepted answers a one-way request with 202 Accepted. The envelope
+// carries the header entries added by the handlers of the request header,
+// but no WS-Addressing headers, as it is not a reply.
+func sendAccepted(ctx context.Context, w http.ResponseWriter) {
+	envelope := Envelope{Body: Body{}}
+	if entries := soapheader.ResponseHeaders(ctx); len(entries) > 0 {
+		envelope.Header = &Header{Entries: entries}
+	}
+
+	output, err := xml.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(xml.Header + string(output)))
+}
+
+// sendFault sends a SOAP fault response. Faults caused by the request are
+// sent with HTTP 400, faults of the service with HTTP 500.
+func sendFault(ctx context.Context, w http.ResponseWriter, fault *soapfault.Error) {
+	envelope := Envelope{
+		Header: responseHeader(ctx, wsaddressing.FaultAction),
+		Body: Body{
+			Fault: fault.SOAP11(),
+		},
+	}
+
+	output, err := xml.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(fault.HTTPStatus())
+	w.Write([]byte(xml.Header + string(output)))
+}
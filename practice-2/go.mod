@@ -2,4 +2,32 @@ module practice-2
 
 go 1.21
 
-require github.com/hooklift/gowsdl v0.5.0 // indirect
+require (
+	github.com/hooklift/gowsdl v0.5.0
+	iso4217 v0.0.0
+	money v0.0.0
+	pricing v0.0.0
+	rates v0.0.0
+	soapfault v0.0.0
+	soapheader v0.0.0
+	wssecurity v0.0.0
+	xmllimit v0.0.0
+)
+
+require gopkg.in/yaml.v3 v3.0.1 // indirect
+
+replace iso4217 => ../iso4217
+
+replace money => ../money
+
+replace pricing => ../pricing
+
+replace rates => ../rates
+
+replace soapfault => ../soapfault
+
+replace soapheader => ../soapheader
+
+replace wssecurity => ../wssecurity
+
+replace xmllimit => ../xmllimit
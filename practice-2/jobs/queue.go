@@ -0,0 +1,403 @@
+// Package jobs is a durable queue of work that is retried until it succeeds.
+// Every job is a file in the queue directory, so submitted jobs survive a
+// restart of the service. A job is deleted once its handler succeeds and
+// moved to the failed subdirectory once it runs out of attempts.
+//
+// Jobs are handled at least once: a job whose handler succeeded just before
+// the service stopped is handled again after the restart.
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job is a unit of work in the queue
+type Job struct {
+	ID string `json:"id"`
+	// Payload describes the work in a format of the handler's choosing. The
+	// handler may change it with SetPayload, e.g. to keep the result of a
+	// step that must not be repeated; the change is saved with the job when
+	// the attempt fails.
+	Payload     json.RawMessage `json:"payload"`
+	Created     time.Time       `json:"created"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+}
+
+// SetPayload sets the payload of a job to the JSON encoding of v
+func (j *Job) SetPayload(v interface{}) error {
+	data, err := marshal(v, "")
+	if err != nil {
+		return err
+	}
+	j.Payload = data
+	return nil
+}
+
+// marshal encodes v as JSON without escaping HTML characters, which are
+// common in payloads holding XML
+func marshal(v interface{}, indent string) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Handler does the work of a job. Jobs whose handler fails are retried
+// with exponential backoff unless the error is permanent, see Permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+// PermanentError marks an error retrying cannot fix
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps a handler error so that the job fails without further attempts
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// ErrFull is returned by Submit when MaxPending jobs are waiting
+var ErrFull = errors.New("job queue is full")
+
+// failedDir is the subdirectory of the jobs that ran out of attempts
+const failedDir = "failed"
+
+// Queue keeps jobs in a directory and hands them to a handler, see Run.
+// It is safe for concurrent use.
+type Queue struct {
+	dir string
+	// now is the clock used for scheduling
+	now func() time.Time
+
+	// MaxPending limits the number of jobs in the queue
+	MaxPending int
+	// MaxAttempts is the number of attempts after which a job fails
+	MaxAttempts int
+	// MinBackoff is the delay after the first failed attempt; it doubles
+	// with every further attempt up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	mu      sync.Mutex
+	jobs    map[string]*Job
+	running map[string]bool
+	// reserved counts the places held by jobs Submit is still saving
+	reserved int
+	// wake tells Run that a job was submitted or finished
+	wake chan struct{}
+}
+
+// Open opens the queue in dir, creating the directory if needed, with the
+// jobs left there by an earlier run. Jobs that cannot be read are moved to
+// the failed subdirectory.
+func Open(dir string) (*Queue, error) {
+	if err := os.MkdirAll(filepath.Join(dir, failedDir), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create job queue: %w", err)
+	}
+	q := &Queue{
+		dir:         dir,
+		now:         time.Now,
+		MaxPending:  10000,
+		MaxAttempts: 8,
+		MinBackoff:  5 * time.Second,
+		MaxBackoff:  10 * time.Minute,
+		jobs:        make(map[string]*Job),
+		running:     make(map[string]bool),
+		wake:        make(chan struct{}, 1),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job queue: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+		case strings.HasSuffix(name, ".tmp"):
+			// Left over from a write that did not complete
+			os.Remove(filepath.Join(dir, name))
+		case strings.HasSuffix(name, ".json"):
+			job, err := readJob(filepath.Join(dir, name))
+			if err != nil {
+				log.Printf("jobs: moving unreadable job %s to %s: %v", name, failedDir, err)
+				os.Rename(filepath.Join(dir, name), filepath.Join(dir, failedDir, name))
+				continue
+			}
+			q.jobs[job.ID] = job
+		}
+	}
+	return q, nil
+}
+
+// readJob reads a job file
+func readJob(path string) (*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	job := new(Job)
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	if job.ID+".json" != filepath.Base(path) {
+		return nil, fmt.Errorf("job ID %q does not match the file name", job.ID)
+	}
+	return job, nil
+}
+
+// Len returns the number of jobs in the queue
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// Submit adds a job with the JSON encoding of payload to the queue. The job
+// is saved before Submit returns; the queue is not locked while it is
+// written, only a place in the queue is reserved for it.
+func (q *Queue) Submit(payload interface{}) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := q.now()
+	job := &Job{ID: id, Created: now, NextAttempt: now}
+	if err := job.SetPayload(payload); err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	if len(q.jobs)+q.reserved >= q.MaxPending {
+		q.mu.Unlock()
+		return nil, ErrFull
+	}
+	q.reserved++
+	q.mu.Unlock()
+
+	err = q.save(job, q.path(job))
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reserved--
+	if err != nil {
+		return nil, err
+	}
+	q.jobs[job.ID] = job
+	q.notify()
+	return job, nil
+}
+
+// Run hands due jobs to the handler in the given number of workers until
+// ctx is cancelled. An attempt the cancellation interrupts does not count.
+func (q *Queue) Run(ctx context.Context, workers int, handler Handler) {
+	work := make(chan *Job)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range work {
+				q.handle(ctx, handler, job)
+			}
+		}()
+	}
+	defer wg.Wait()
+	defer close(work)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		job, wait := q.next()
+		if job != nil {
+			select {
+			case work <- job:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// next returns the due job that has waited longest, marking it as running,
+// or nil and the time until the next job is due
+func (q *Queue) next() (*Job, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	var due *Job
+	wait := time.Hour
+	for id, job := range q.jobs {
+		if q.running[id] {
+			continue
+		}
+		if until := job.NextAttempt.Sub(now); until > 0 {
+			if until < wait {
+				wait = until
+			}
+			continue
+		}
+		if due == nil || job.NextAttempt.Before(due.NextAttempt) {
+			due = job
+		}
+	}
+	if due != nil {
+		q.running[due.ID] = true
+	}
+	return due, wait
+}
+
+// handle runs one attempt of a job and saves its outcome. The job stays
+// marked as running until then, so its file is written without locking the
+// queue.
+func (q *Queue) handle(ctx context.Context, handler Handler, job *Job) {
+	err := handler(ctx, job)
+
+	if err != nil && ctx.Err() != nil {
+		q.finish(job, false)
+		return
+	}
+	if err == nil {
+		if err := os.Remove(q.path(job)); err != nil {
+			log.Printf("jobs: failed to remove finished job %s: %v", job.ID, err)
+		}
+		q.finish(job, true)
+		return
+	}
+
+	job.Attempts++
+	job.LastError = err.Error()
+	var permanent *PermanentError
+	if errors.As(err, &permanent) || job.Attempts >= q.MaxAttempts {
+		log.Printf("jobs: job %s failed after %d attempts: %v", job.ID, job.Attempts, err)
+		if err := q.save(job, filepath.Join(q.dir, failedDir, job.ID+".json")); err != nil {
+			log.Printf("jobs: failed to save failed job %s: %v", job.ID, err)
+		}
+		os.Remove(q.path(job))
+		q.finish(job, true)
+		return
+	}
+
+	job.NextAttempt = q.now().Add(q.backoff(job.Attempts))
+	log.Printf("jobs: attempt %d of job %s failed, retrying at %s: %v",
+		job.Attempts, job.ID, job.NextAttempt.Format(time.RFC3339), err)
+	if err := q.save(job, q.path(job)); err != nil {
+		log.Printf("jobs: failed to save job %s: %v", job.ID, err)
+	}
+	q.finish(job, false)
+}
+
+// finish ends an attempt of a job, removing the job from the queue if done
+func (q *Queue) finish(job *Job, done bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.running, job.ID)
+	if done {
+		delete(q.jobs, job.ID)
+	}
+	q.notify()
+}
+
+// backoff returns the delay after the given number of failed attempts
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.MinBackoff
+	for i := 1; i < attempts && delay < q.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.MaxBackoff {
+		delay = q.MaxBackoff
+	}
+	return delay
+}
+
+// notify wakes Run without blocking
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// path returns the file of a job in the queue
+func (q *Queue) path(job *Job) string {
+	return filepath.Join(q.dir, job.ID+".json")
+}
+
+// save writes a job to a temporary file and renames it, so that a crash
+// leaves either the old or the new version of the job
+func (q *Queue) save(job *Job, path string) error {
+	data, err := marshal(job, "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(q.dir, job.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// newID returns a random 128-bit job ID in hex
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -2,212 +2,523 @@ package main
 
 import (
 	"context"
-	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"iso4217"
+	"money"
 	"practice-2/currency"
+	"practice-2/dispatch"
+	"practice-2/jobs"
+	"practice-2/quotes"
+	"practice-2/schema"
+	"practice-2/wsdldoc"
+	"pricing"
+	"rates"
+	"soapfault"
+	"wssecurity"
+
+	"github.com/hooklift/gowsdl/soap"
 )
 
+// Simple rates against USD for demonstration purposes, used when RATES_SOURCE is not set
+var defaultRates = map[string]money.Decimal{
+	"EUR": money.MustParse("0.93"),
+	"GBP": money.MustParse("0.79"),
+	"JPY": money.MustParse("152"),
+	"UAH": money.MustParse("41.5"),
+}
+
+// maxBatchItems limits the number of conversions in one ConvertCurrencyBatch request
+const maxBatchItems = 5000
+
+// errFutureValueDate is returned for conversions priced at a date that has not come yet
+var errFutureValueDate = errors.New("valueDate is in the future")
+
 // CurrencyService implements the SOAP service
-type CurrencyService struct{}
+type CurrencyService struct {
+	rates      rates.Provider
+	quotes     *quotes.Store
+	currencies *iso4217.Registry
+	pricing    *pricing.Schedule
+	rounding   money.RoundingMode
+	// jobs holds the conversions of SubmitConversion until they are delivered
+	jobs *jobs.Queue
+	// callbacks restricts where they are delivered, see SetCallbackPolicy
+	callbacks callbackPolicy
+}
+
+// NewCurrencyService creates a currency service that validates currencies against the
+// registry, prices conversions with the given rate provider plus the spreads and fees of
+// the pricing schedule, locks quoted rates in the quote store, rounds amounts to the
+// target currency's minor units using the given mode and queues submitted conversions
+// in the job queue, whose jobs DeliverConversion handles
+func NewCurrencyService(provider rates.Provider, quoteStore *quotes.Store, currencies *iso4217.Registry, schedule *pricing.Schedule, rounding money.RoundingMode, jobQueue *jobs.Queue) *CurrencyService {
+	return &CurrencyService{rates: provider, quotes: quoteStore, currencies: currencies, pricing: schedule, rounding: rounding,
+		jobs: jobQueue, callbacks: newCallbackPolicy(nil, false)}
+}
 
 // ConvertCurrency implements the currency conversion functionality
 func (s *CurrencyService) ConvertCurrency(request *currency.ConvertCurrencyRequest) (*currency.ConvertCurrencyResponse, error) {
-	// Simple rates for demonstration purposes
-	rates := map[string]map[string]float64{
-		"USD": {"EUR": 0.93, "GBP": 0.79, "JPY": 152.0, "UAH": 41.5},
-		"EUR": {"USD": 1.07, "GBP": 0.85, "JPY": 163.0, "UAH": 44.6},
-		"GBP": {"USD": 1.26, "EUR": 1.18, "JPY": 192.0, "UAH": 52.5},
-		"JPY": {"USD": 0.0066, "EUR": 0.0061, "GBP": 0.0052, "UAH": 0.27},
-		"UAH": {"USD": 0.024, "EUR": 0.022, "GBP": 0.019, "JPY": 3.7},
-	}
+	return s.ConvertCurrencyContext(context.Background(), request)
+}
 
+// ConvertCurrencyContext implements the context-aware version of the conversion
+// functionality; rate lookups give up once the context is done
+func (s *CurrencyService) ConvertCurrencyContext(ctx context.Context, request *currency.ConvertCurrencyRequest) (*currency.ConvertCurrencyResponse, error) {
 	from := request.FromCurrency
 	to := request.ToCurrency
 	amount := request.Amount
 
-	rate, ok := rates[from][to]
-	if !ok {
-		return nil, fmt.Errorf("conversion rate not found for %s to %s", from, to)
+	if _, err := s.currencies.Validate(from); err != nil {
+		return nil, fmt.Errorf("fromCurrency: %w", err)
+	}
+	target, err := s.currencies.Validate(to)
+	if err != nil {
+		return nil, fmt.Errorf("toCurrency: %w", err)
 	}
 
-	convertedAmount := amount * rate
+	var rate rates.Rate
+	if request.ValueDate == (soap.XSDDate{}) {
+		rate, err = s.rates.RateContext(ctx, from, to)
+	} else {
+		valueDate := xsdDay(request.ValueDate)
+		if valueDate.After(rates.Day(time.Now())) {
+			return nil, fmt.Errorf("%w: %s", errFutureValueDate, valueDate.Format(time.DateOnly))
+		}
+		rate, err = s.rates.RateOnContext(ctx, valueDate, from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	price, err := s.pricing.Price(amount, rate.Value, from, to, target.MinorUnits, rates.Scale, s.rounding)
+	if err != nil {
+		return nil, err
+	}
 
 	return &currency.ConvertCurrencyResponse{
-		ConvertedAmount: convertedAmount,
+		ConvertedAmount: price.ConvertedAmount,
 		FromCurrency:    from,
 		ToCurrency:      to,
-		Rate:            rate,
+		Rate:            price.AppliedRate,
+		Path:            &currency.ConversionPath{Currency: rate.Path},
+		ValueDate:       request.ValueDate,
+		RateDate:        soap.CreateXsdDate(rate.Date, false),
+		MidRate:         price.MidRate,
+		AppliedRate:     price.AppliedRate,
+		Fee:             price.Fee,
+		NetAmount:       price.NetAmount,
 	}, nil
 }
 
-// ConvertCurrencyContext implements the context-aware version of the conversion functionality
-func (s *CurrencyService) ConvertCurrencyContext(ctx context.Context, request *currency.ConvertCurrencyRequest) (*currency.ConvertCurrencyResponse, error) {
-	return s.ConvertCurrency(request)
+// ConvertCurrencyBatch converts every item of the request independently.
+// Results follow the order of the items; an item that cannot be converted
+// gets a fault in its result instead of failing the whole batch.
+func (s *CurrencyService) ConvertCurrencyBatch(request *currency.ConvertCurrencyBatchRequest) (*currency.ConvertCurrencyBatchResponse, error) {
+	return s.ConvertCurrencyBatchContext(context.Background(), request)
 }
 
-// SOAPEnvelope is the root element for SOAP requests/responses
-type SOAPEnvelope struct {
-	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
-	Body    SOAPBody
-}
-
-// SOAPBody contains the actual SOAP message
-type SOAPBody struct {
-	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
-	Content interface{}
-	Fault   *SOAPFault `xml:",omitempty"`
-}
-
-// SOAPFault represents a SOAP error
-type SOAPFault struct {
-	XMLName     xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault"`
-	FaultCode   string   `xml:"faultcode"`
-	FaultString string   `xml:"faultstring"`
-	Detail      string   `xml:"detail,omitempty"`
-}
+// ConvertCurrencyBatchContext implements the context-aware version of
+// ConvertCurrencyBatch. Once the context is done the whole batch fails
+// with its error, as the remaining items would fail the same way.
+func (s *CurrencyService) ConvertCurrencyBatchContext(ctx context.Context, request *currency.ConvertCurrencyBatchRequest) (*currency.ConvertCurrencyBatchResponse, error) {
+	if len(request.Item) == 0 {
+		return nil, soapfault.New(soapfault.Client, soapfault.InvalidBatch, "Invalid batch",
+			fmt.Errorf("batch contains no items"))
+	}
+	if len(request.Item) > maxBatchItems {
+		return nil, soapfault.New(soapfault.Client, soapfault.InvalidBatch, "Invalid batch",
+			fmt.Errorf("batch contains %d items, at most %d are allowed", len(request.Item), maxBatchItems))
+	}
 
-// SOAPHandler handles SOAP requests for currency conversion
-func (s *CurrencyService) SOAPHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// 1. Basic request validation
-		if r.Method != "POST" {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			return
+	response := &currency.ConvertCurrencyBatchResponse{
+		Result: make([]*currency.ConversionItemResult, 0, len(request.Item)),
+	}
+	for _, item := range request.Item {
+		result := &currency.ConversionItemResult{Id: item.Id}
+
+		converted, err := s.ConvertCurrencyContext(ctx, &currency.ConvertCurrencyRequest{
+			Amount:       item.Amount,
+			FromCurrency: item.FromCurrency,
+			ToCurrency:   item.ToCurrency,
+			ValueDate:    item.ValueDate,
+		})
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
 		}
-		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
-
-		// 2. Read request body
-		body, err := io.ReadAll(r.Body)
-		defer r.Body.Close()
 		if err != nil {
-			sendSOAPFault(w, "Failed to read request body", err.Error())
-			return
-		}
-
-		// 3. Parse request with flexible structure
-		var requestData struct {
-			XMLName xml.Name `xml:"Envelope"`
-			Body    struct {
-				XMLName xml.Name `xml:"Body"`
-				Request struct {
-					XMLName      xml.Name `xml:"ConvertCurrencyRequest"`
-					Amount       float64  `xml:"amount"`
-					FromCurrency string   `xml:"fromCurrency"`
-					ToCurrency   string   `xml:"toCurrency"`
-				}
+			// The detail message is left out of Server faults, as in
+			// the faults of whole requests
+			fault := faultFor(err)
+			result.Fault = &currency.ItemFault{
+				Faultcode:   string(fault.Code),
+				Faultstring: fault.String,
+				ErrorCode:   string(fault.ErrorCode),
+				Detail:      fault.Detail().Message,
+			}
+		} else {
+			result.Conversion = &currency.ConversionResult{
+				ConvertedAmount: converted.ConvertedAmount,
+				FromCurrency:    converted.FromCurrency,
+				ToCurrency:      converted.ToCurrency,
+				Rate:            converted.Rate,
+				Path:            converted.Path,
+				ValueDate:       converted.ValueDate,
+				RateDate:        converted.RateDate,
+				MidRate:         converted.MidRate,
+				AppliedRate:     converted.AppliedRate,
+				Fee:             converted.Fee,
+				NetAmount:       converted.NetAmount,
 			}
 		}
+		response.Result = append(response.Result, result)
+	}
+	return response, nil
+}
 
-		if err := xml.Unmarshal(body, &requestData); err != nil {
-			sendSOAPFault(w, "Failed to parse request", err.Error())
-			return
-		}
+// GetQuote locks the current rate for a currency pair until the quote expires
+func (s *CurrencyService) GetQuote(request *currency.GetQuoteRequest) (*currency.GetQuoteResponse, error) {
+	return s.GetQuoteContext(context.Background(), request)
+}
 
-		// 4. Create properly typed request
-		request := &currency.ConvertCurrencyRequest{
-			Amount:       requestData.Body.Request.Amount,
-			FromCurrency: requestData.Body.Request.FromCurrency,
-			ToCurrency:   requestData.Body.Request.ToCurrency,
-		}
+// GetQuoteContext implements the context-aware version of GetQuote
+func (s *CurrencyService) GetQuoteContext(ctx context.Context, request *currency.GetQuoteRequest) (*currency.GetQuoteResponse, error) {
+	from := request.FromCurrency
+	to := request.ToCurrency
 
-		// 5. Process the request
-		response, err := s.ConvertCurrency(request)
-		if err != nil {
-			sendSOAPFault(w, "Failed to process request", err.Error())
-			return
-		}
+	if _, err := s.currencies.Validate(from); err != nil {
+		return nil, fmt.Errorf("fromCurrency: %w", err)
+	}
+	if _, err := s.currencies.Validate(to); err != nil {
+		return nil, fmt.Errorf("toCurrency: %w", err)
+	}
 
-		// 6. Send response
-		sendSOAPResponse(w, response)
+	rate, err := s.rates.RateContext(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	applied := s.pricing.Rate(rate.Value, from, to, rates.Scale)
+	quote, err := s.quotes.Issue(from, to, rate, applied)
+	if err != nil {
+		return nil, err
 	}
+
+	return &currency.GetQuoteResponse{
+		QuoteId:      quote.ID,
+		FromCurrency: from,
+		ToCurrency:   to,
+		Rate:         applied,
+		Path:         &currency.ConversionPath{Currency: rate.Path},
+		RateDate:     soap.CreateXsdDate(rate.Date, false),
+		ExpiresAt:    soap.CreateXsdDateTime(quote.ExpiresAt.UTC().Truncate(time.Second), true),
+		MidRate:      rate.Value,
+		AppliedRate:  applied,
+	}, nil
 }
 
-// sendSOAPResponse sends a successful SOAP response
-func sendSOAPResponse(w http.ResponseWriter, content interface{}) {
-	responseEnvelope := SOAPEnvelope{
-		Body: SOAPBody{
-			Content: content,
-		},
+// ConvertWithQuote converts an amount at the rate locked by an unexpired quote,
+// regardless of how the rates have changed since the quote was issued
+func (s *CurrencyService) ConvertWithQuote(request *currency.ConvertWithQuoteRequest) (*currency.ConvertWithQuoteResponse, error) {
+	return s.ConvertWithQuoteContext(context.Background(), request)
+}
+
+// ConvertWithQuoteContext implements the context-aware version of
+// ConvertWithQuote; a request whose context is done is not converted
+func (s *CurrencyService) ConvertWithQuoteContext(ctx context.Context, request *currency.ConvertWithQuoteRequest) (*currency.ConvertWithQuoteResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	quote, err := s.quotes.Get(request.QuoteId)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.currencies.Validate(quote.To)
+	if err != nil {
+		return nil, fmt.Errorf("toCurrency: %w", err)
 	}
 
-	output, err := xml.MarshalIndent(responseEnvelope, "", "  ")
+	price, err := s.pricing.PriceAt(request.Amount, quote.Rate.Value, quote.Applied,
+		quote.From, quote.To, target.MinorUnits, s.rounding)
 	if err != nil {
-		sendSOAPFault(w, "Failed to encode response", err.Error())
-		return
+		return nil, err
 	}
 
-	w.Write([]byte(xml.Header + string(output)))
+	return &currency.ConvertWithQuoteResponse{
+		QuoteId:         quote.ID,
+		ConvertedAmount: price.ConvertedAmount,
+		FromCurrency:    quote.From,
+		ToCurrency:      quote.To,
+		Rate:            price.AppliedRate,
+		Path:            &currency.ConversionPath{Currency: quote.Rate.Path},
+		RateDate:        soap.CreateXsdDate(quote.Rate.Date, false),
+		MidRate:         price.MidRate,
+		AppliedRate:     price.AppliedRate,
+		Fee:             price.Fee,
+		NetAmount:       price.NetAmount,
+	}, nil
 }
 
-// sendSOAPFault sends a SOAP fault response
-func sendSOAPFault(w http.ResponseWriter, faultString, detail string) {
-	fault := SOAPFault{
-		FaultCode:   "Server",
-		FaultString: faultString,
-		Detail:      detail,
+// faultFor maps an error of the service to a SOAP fault. Errors caused by
+// the request are Client faults with the matching error code; any other
+// error is a Server fault. It also classifies failed batch items.
+func faultFor(err error) *soapfault.Error {
+	var unknown *iso4217.UnknownCurrencyError
+	var inactive *iso4217.InactiveCurrencyError
+	var notFound *rates.NotFoundError
+	var noSnapshot *rates.NoSnapshotError
+	var invalidAmount *pricing.InvalidAmountError
+	var feeExceeds *pricing.FeeExceedsAmountError
+	var quoteNotFound *quotes.NotFoundError
+	var quoteExpired *quotes.ExpiredError
+	switch {
+	case errors.As(err, &unknown):
+		return soapfault.New(soapfault.Client, soapfault.UnknownCurrency, "Invalid currency", err)
+	case errors.As(err, &inactive):
+		return soapfault.New(soapfault.Client, soapfault.InactiveCurrency, "Invalid currency", err)
+	case errors.As(err, &notFound):
+		return soapfault.New(soapfault.Client, soapfault.RateNotFound, "Invalid currency pair", err)
+	case errors.Is(err, errFutureValueDate):
+		return soapfault.New(soapfault.Client, soapfault.InvalidValueDate, "Invalid valueDate", err)
+	case errors.As(err, &noSnapshot):
+		return soapfault.New(soapfault.Client, soapfault.NoRatesForDate, "No exchange rates for valueDate", err)
+	case errors.As(err, &invalidAmount):
+		return soapfault.New(soapfault.Client, soapfault.InvalidAmount, "Invalid amount", err)
+	case errors.As(err, &feeExceeds):
+		return soapfault.New(soapfault.Client, soapfault.AmountTooSmall, "Amount too small", err)
+	case errors.As(err, &quoteNotFound):
+		return soapfault.New(soapfault.Client, soapfault.QuoteNotFound, "Unknown quote", err)
+	case errors.As(err, &quoteExpired):
+		return soapfault.New(soapfault.Client, soapfault.QuoteExpired, "Quote expired", err)
+	case errors.Is(err, jobs.ErrFull):
+		return soapfault.New(soapfault.Server, soapfault.QueueFull, "Service busy", err)
+	default:
+		return soapfault.From(err)
 	}
+}
 
-	envelope := SOAPEnvelope{
-		Body: SOAPBody{
-			Fault: &fault,
-		},
-	}
+// xsdDay returns the calendar day of an xsd:date as midnight UTC
+func xsdDay(date soap.XSDDate) time.Time {
+	t := date.ToGoTime()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
 
-	output, err := xml.MarshalIndent(envelope, "", "  ")
-	if err != nil {
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
+// ListCurrencies returns the currencies the service supports
+func (s *CurrencyService) ListCurrencies(request *currency.ListCurrenciesRequest) (*currency.ListCurrenciesResponse, error) {
+	return s.ListCurrenciesContext(context.Background(), request)
+}
 
-	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
-	w.WriteHeader(http.StatusInternalServerError)
-	w.Write([]byte(xml.Header + string(output)))
+// ListCurrenciesContext implements the context-aware version of
+// ListCurrencies; a request whose context is done is not answered
+func (s *CurrencyService) ListCurrenciesContext(ctx context.Context, request *currency.ListCurrenciesRequest) (*currency.ListCurrenciesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	response := &currency.ListCurrenciesResponse{}
+	for _, c := range s.currencies.List(request.IncludeInactive) {
+		response.Currency = append(response.Currency, &currency.CurrencyInfo{
+			Code:        c.Code,
+			NumericCode: c.Numeric,
+			Name:        c.Name,
+			MinorUnits:  int32(c.MinorUnits),
+			Active:      c.Active,
+		})
+	}
+	return response, nil
 }
 
-// WSDLFileServer serves static WSDL files
-func WSDLFileServer(dir string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Get the file name from the URL
-		fileName := filepath.Base(r.URL.Path)
-		filePath := filepath.Join(dir, fileName)
+// CurrencyService implements the port type of wsdl/currency.wsdl
+var _ currency.CurrencyConversionPortType = (*CurrencyService)(nil)
+
+// SOAPHandler routes SOAP requests to the currency service operations,
+// checking request elements against the schema of the service WSDL. The
+// operations are registered by the server binding wsdl_gen.sh generates.
+func (s *CurrencyService) SOAPHandler(wsdlSchema *schema.Schema) *dispatch.Dispatcher {
+	d := dispatch.New(wsdlSchema)
+	d.FaultFor = faultFor
+	currency.RegisterCurrencyConversionPortType(d, s)
+	return d
+}
 
-		// Check if the file exists
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			http.NotFound(w, r)
-			return
+// setOperationTimeouts applies a comma separated list of operation=duration pairs
+func setOperationTimeouts(d *dispatch.Dispatcher, value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
 		}
-
-		// Read the file
-		data, err := os.ReadFile(filePath)
+		name, duration, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("expected operation=duration, got %q", pair)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(duration))
 		if err != nil {
-			http.Error(w, "Error reading WSDL file", http.StatusInternalServerError)
-			return
+			return err
+		}
+		if err := d.SetTimeout(strings.TrimSpace(name), timeout); err != nil {
+			return err
 		}
-
-		// Set content type and write the file
-		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
-		w.Write(data)
 	}
+	return nil
 }
 
 func main() {
-	// Create and register the currency service
-	currencyService := &CurrencyService{}
+	// Create the rate engine, loading rates from RATES_SOURCE when set
+	engine, err := rates.NewEngine("USD", defaultRates)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if source := os.Getenv("RATES_SOURCE"); source != "" {
+		interval := time.Minute
+		if value := os.Getenv("RATES_POLL_INTERVAL"); value != "" {
+			if interval, err = time.ParseDuration(value); err != nil || interval <= 0 {
+				log.Fatalf("invalid RATES_POLL_INTERVAL %q", value)
+			}
+		}
+		watcher := rates.NewWatcher(source, engine, interval)
+		if err := watcher.Reload(); err != nil {
+			log.Fatal(err)
+		}
+		go watcher.Run(context.Background())
+		log.Printf("Loaded exchange rates from %s, checking for changes every %s", source, interval)
+	} else {
+		log.Println("RATES_SOURCE is not set, using demonstration rates")
+	}
+	rounding := money.HalfUp
+	if name := os.Getenv("ROUNDING_MODE"); name != "" {
+		if rounding, err = money.ParseRoundingMode(name); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Quotes lock a rate for QUOTE_TTL; expired quotes are swept at the same interval
+	quoteTTL := time.Minute
+	if value := os.Getenv("QUOTE_TTL"); value != "" {
+		if quoteTTL, err = time.ParseDuration(value); err != nil || quoteTTL <= 0 {
+			log.Fatalf("invalid QUOTE_TTL %q", value)
+		}
+	}
+	quoteStore := quotes.NewStore(quoteTTL)
+	go quoteStore.Run(context.Background(), quoteTTL)
+
+	// Spreads and fees come from PRICING_FILE, a JSON or YAML file; without one,
+	// conversions use the mid rate
+	schedule := &pricing.Schedule{}
+	if path := os.Getenv("PRICING_FILE"); path != "" {
+		if schedule, err = pricing.LoadFile(path); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// Submitted conversions wait in JOB_QUEUE_DIR until JOB_WORKERS workers
+	// have delivered them, trying each one up to JOB_MAX_ATTEMPTS times
+	queueDir := "queue"
+	if dir := os.Getenv("JOB_QUEUE_DIR"); dir != "" {
+		queueDir = dir
+	}
+	jobQueue, err := jobs.Open(queueDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if value := os.Getenv("JOB_MAX_ATTEMPTS"); value != "" {
+		if jobQueue.MaxAttempts, err = strconv.Atoi(value); err != nil || jobQueue.MaxAttempts <= 0 {
+			log.Fatalf("invalid JOB_MAX_ATTEMPTS %q", value)
+		}
+	}
+	workers := 4
+	if value := os.Getenv("JOB_WORKERS"); value != "" {
+		if workers, err = strconv.Atoi(value); err != nil || workers <= 0 {
+			log.Fatalf("invalid JOB_WORKERS %q", value)
+		}
+	}
+
+	currencyService := NewCurrencyService(engine, quoteStore, iso4217.Default, schedule, rounding, jobQueue)
+	// Results are only delivered to public addresses, and to the hosts of the
+	// comma-separated CALLBACK_ALLOWED_HOSTS if set. CALLBACK_ALLOW_PRIVATE
+	// also allows loopback and private addresses, e.g. for local development.
+	var callbackHosts []string
+	for _, host := range strings.Split(os.Getenv("CALLBACK_ALLOWED_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			callbackHosts = append(callbackHosts, host)
+		}
+	}
+	currencyService.SetCallbackPolicy(callbackHosts, os.Getenv("CALLBACK_ALLOW_PRIVATE") != "")
+	go jobQueue.Run(context.Background(), workers, currencyService.DeliverConversion)
+	log.Printf("Delivering submitted conversions from %s with %d workers, %d queued", queueDir, workers, jobQueue.Len())
 
 	// Register the SOAP handler for the currency service
-	http.HandleFunc("/soap/convert-currency", currencyService.SOAPHandler())
+	wsdlSchema, err := schema.Load("wsdl/currency.wsdl")
+	if err != nil {
+		log.Fatal(err)
+	}
+	handler := currencyService.SOAPHandler(wsdlSchema)
+	// SOAP_DEBUG also validates every response against the schema
+	handler.ValidateResponses = os.Getenv("SOAP_DEBUG") != ""
+	// Operations run for at most SOAP_TIMEOUT; SOAP_OPERATION_TIMEOUTS sets
+	// limits for single operations, e.g. "ConvertCurrencyBatch=2m,GetQuote=5s"
+	handler.Timeout = 30 * time.Second
+	if value := os.Getenv("SOAP_TIMEOUT"); value != "" {
+		if handler.Timeout, err = time.ParseDuration(value); err != nil || handler.Timeout <= 0 {
+			log.Fatalf("invalid SOAP_TIMEOUT %q", value)
+		}
+	}
+	if err := setOperationTimeouts(handler, os.Getenv("SOAP_OPERATION_TIMEOUTS")); err != nil {
+		log.Fatalf("invalid SOAP_OPERATION_TIMEOUTS: %v", err)
+	}
+	// Requests are limited to SOAP_MAX_BODY_SIZE bytes, elements nested
+	// SOAP_MAX_DEPTH deep and SOAP_MAX_ATTRIBUTES attributes per element;
+	// 0 removes a limit
+	if value := os.Getenv("SOAP_MAX_BODY_SIZE"); value != "" {
+		if handler.Limits.MaxBodySize, err = strconv.ParseInt(value, 10, 64); err != nil || handler.Limits.MaxBodySize < 0 {
+			log.Fatalf("invalid SOAP_MAX_BODY_SIZE %q", value)
+		}
+	}
+	if value := os.Getenv("SOAP_MAX_DEPTH"); value != "" {
+		if handler.Limits.MaxDepth, err = strconv.Atoi(value); err != nil || handler.Limits.MaxDepth < 0 {
+			log.Fatalf("invalid SOAP_MAX_DEPTH %q", value)
+		}
+	}
+	if value := os.Getenv("SOAP_MAX_ATTRIBUTES"); value != "" {
+		if handler.Limits.MaxAttributes, err = strconv.Atoi(value); err != nil || handler.Limits.MaxAttributes < 0 {
+			log.Fatalf("invalid SOAP_MAX_ATTRIBUTES %q", value)
+		}
+	}
+	// Requests authenticate with a WS-Security UsernameToken against the
+	// users of WSS_CREDENTIALS_FILE; WSS_REQUIRE_TIMESTAMP also requires a
+	// wsu:Timestamp
+	if path := os.Getenv("WSS_CREDENTIALS_FILE"); path != "" {
+		credentials, err := wssecurity.LoadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		verifier := wssecurity.NewVerifier(credentials)
+		verifier.RequireTimestamp = os.Getenv("WSS_REQUIRE_TIMESTAMP") != ""
+		handler.RequireAuthentication(verifier)
+		log.Printf("Authenticating SOAP requests with the %d users of %s", len(credentials), path)
+	} else {
+		log.Println("WSS_CREDENTIALS_FILE is not set, SOAP requests are not authenticated")
+	}
 
-	// Serve WSDL files
-	http.HandleFunc("/wsdl/", WSDLFileServer("wsdl"))
+	// Serve the WSDL and its schemas with the address clients reach the
+	// server at: PUBLIC_URL when set, otherwise the request's host, or the
+	// X-Forwarded-* headers of a proxy with TRUST_FORWARDED_HEADERS
+	documents, err := wsdldoc.NewServer("wsdl", "/wsdl/", "/soap/convert-currency")
+	if err != nil {
+		log.Fatal(err)
+	}
+	documents.BaseURL = os.Getenv("PUBLIC_URL")
+	documents.TrustForwarded = os.Getenv("TRUST_FORWARDED_HEADERS") != ""
+	http.Handle("/wsdl/", documents)
+	http.Handle("/soap/convert-currency", documents.WithWSDL(handler, "currency.wsdl"))
 
 	// Start the HTTP server
 	fmt.Println("Starting SOAP server at http://localhost:8080")
-	fmt.Println("WSDL: http://localhost:8080/wsdl/currency.wsdl")
+	fmt.Println("WSDL: http://localhost:8080/soap/convert-currency?wsdl")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
@@ -0,0 +1,154 @@
+// Package quotes locks exchange rates for a limited time so a conversion
+// can be priced once and executed later at the same rate.
+package quotes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"money"
+	"rates"
+)
+
+// Quote is a rate locked for a currency pair until ExpiresAt
+type Quote struct {
+	ID   string
+	From string
+	To   string
+	// Rate is the mid rate at the time of the quote
+	Rate rates.Rate
+	// Applied is the customer rate after the spread
+	Applied   money.Decimal
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the quote can no longer be used at time t
+func (q Quote) Expired(t time.Time) bool {
+	return !t.Before(q.ExpiresAt)
+}
+
+// NotFoundError is returned for quote IDs the store does not know,
+// including quotes that have already been swept
+type NotFoundError struct {
+	ID string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("quote %q not found", e.ID)
+}
+
+// ExpiredError is returned for quotes past their expiry
+type ExpiredError struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+func (e *ExpiredError) Error() string {
+	return fmt.Sprintf("quote %q expired at %s", e.ID, e.ExpiresAt.Format(time.RFC3339))
+}
+
+// Store keeps issued quotes until they expire.
+// It is safe for concurrent use.
+type Store struct {
+	ttl time.Duration
+	// now is the clock used for expiry
+	now func() time.Time
+
+	mu     sync.Mutex
+	quotes map[string]Quote
+}
+
+// NewStore creates a store whose quotes are valid for ttl after issue
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, now: time.Now, quotes: make(map[string]Quote)}
+}
+
+// TTL returns how long issued quotes stay valid
+func (s *Store) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue locks the mid and applied rate for the pair and returns the new quote
+func (s *Store) Issue(from, to string, rate rates.Rate, applied money.Decimal) (Quote, error) {
+	id, err := newID()
+	if err != nil {
+		return Quote{}, err
+	}
+
+	now := s.now()
+	quote := Quote{
+		ID:        id,
+		From:      from,
+		To:        to,
+		Rate:      rate,
+		Applied:   applied,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotes[id] = quote
+	return quote, nil
+}
+
+// Get returns an unexpired quote by ID
+func (s *Store) Get(id string) (Quote, error) {
+	s.mu.Lock()
+	quote, ok := s.quotes[id]
+	s.mu.Unlock()
+
+	if !ok {
+		return Quote{}, &NotFoundError{ID: id}
+	}
+	if quote.Expired(s.now()) {
+		return Quote{}, &ExpiredError{ID: id, ExpiresAt: quote.ExpiresAt}
+	}
+	return quote, nil
+}
+
+// Sweep removes expired quotes and returns how many were removed
+func (s *Store) Sweep() int {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for id, quote := range s.quotes {
+		if quote.Expired(now) {
+			delete(s.quotes, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Run sweeps expired quotes every interval until ctx is cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep()
+		}
+	}
+}
+
+// newID returns a random 128-bit quote ID in hex
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate quote ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
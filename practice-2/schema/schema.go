@@ -0,0 +1,355 @@
+// Package schema reads the XML Schema embedded in a WSDL document and
+// validates SOAP body elements against it.
+package schema
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Namespace is the XML Schema namespace of the built-in simple types
+const Namespace = "http://www.w3.org/2001/XMLSchema"
+
+// Unbounded is the MaxOccurs of elements declared with maxOccurs="unbounded"
+const Unbounded = -1
+
+// Schema is the compiled structure of a WSDL's xsd:schema
+type Schema struct {
+	// Namespace is the target namespace of the schema
+	Namespace string
+	// Qualified is set for elementFormDefault="qualified", where local
+	// elements are in the target namespace instead of in no namespace
+	Qualified bool
+	// Elements are the global element declarations by local name
+	Elements map[string]*Element
+}
+
+// Element is an element declaration
+type Element struct {
+	Name      string
+	MinOccurs int
+	MaxOccurs int
+	// Type is the built-in simple type of the element, e.g. xsd:decimal;
+	// it is empty for elements with complex content
+	Type xml.Name
+	// Complex is the content model of elements with complex content
+	Complex *ComplexType
+}
+
+// ComplexType is a named or anonymous complex type
+type ComplexType struct {
+	Name    string
+	Content *Group
+}
+
+// Group is an xsd:sequence or xsd:choice of particles
+type Group struct {
+	Choice    bool
+	MinOccurs int
+	MaxOccurs int
+	Particles []Particle
+}
+
+// Particle is either an element or a nested group
+type Particle struct {
+	Element *Element
+	Group   *Group
+}
+
+// wsdlDocument holds the parts of a WSDL document the schema is read from
+type wsdlDocument struct {
+	Attrs []xml.Attr `xml:",any,attr"`
+	Types struct {
+		Schemas []xsdSchema `xml:"http://www.w3.org/2001/XMLSchema schema"`
+	} `xml:"http://schemas.xmlsoap.org/wsdl/ types"`
+}
+
+type xsdSchema struct {
+	Attrs              []xml.Attr       `xml:",any,attr"`
+	TargetNamespace    string           `xml:"targetNamespace,attr"`
+	ElementFormDefault string           `xml:"elementFormDefault,attr"`
+	Elements           []xsdElement     `xml:"http://www.w3.org/2001/XMLSchema element"`
+	ComplexTypes       []xsdComplexType `xml:"http://www.w3.org/2001/XMLSchema complexType"`
+}
+
+type xsdElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr"`
+	MinOccurs   string          `xml:"minOccurs,attr"`
+	MaxOccurs   string          `xml:"maxOccurs,attr"`
+	ComplexType *xsdComplexType `xml:"http://www.w3.org/2001/XMLSchema complexType"`
+}
+
+type xsdComplexType struct {
+	Name     string    `xml:"name,attr"`
+	Sequence *xsdGroup `xml:"http://www.w3.org/2001/XMLSchema sequence"`
+	Choice   *xsdGroup `xml:"http://www.w3.org/2001/XMLSchema choice"`
+}
+
+// xsdGroup keeps its element, sequence and choice children in document order
+type xsdGroup struct {
+	MinOccurs string
+	MaxOccurs string
+	Items     []xsdItem
+}
+
+type xsdItem struct {
+	Element  *xsdElement
+	Sequence *xsdGroup
+	Choice   *xsdGroup
+}
+
+// UnmarshalXML implements xml.Unmarshaler
+func (g *xsdGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "minOccurs":
+			g.MinOccurs = attr.Value
+		case "maxOccurs":
+			g.MaxOccurs = attr.Value
+		}
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var item xsdItem
+			switch {
+			case t.Name.Space == Namespace && t.Name.Local == "element":
+				item.Element = &xsdElement{}
+				err = d.DecodeElement(item.Element, &t)
+			case t.Name.Space == Namespace && t.Name.Local == "sequence":
+				item.Sequence = &xsdGroup{}
+				err = d.DecodeElement(item.Sequence, &t)
+			case t.Name.Space == Namespace && t.Name.Local == "choice":
+				item.Choice = &xsdGroup{}
+				err = d.DecodeElement(item.Choice, &t)
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+			if item != (xsdItem{}) {
+				g.Items = append(g.Items, item)
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Load reads the schema from the types section of a WSDL file
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse reads the schema from the types section of a WSDL document.
+// Only the constructs used by document/literal services are supported:
+// global elements, named and anonymous complex types, sequences, choices
+// and built-in simple types.
+func Parse(data []byte) (*Schema, error) {
+	var doc wsdlDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid WSDL: %w", err)
+	}
+	if len(doc.Types.Schemas) != 1 {
+		return nil, fmt.Errorf("expected one xsd:schema in WSDL types, found %d", len(doc.Types.Schemas))
+	}
+	raw := doc.Types.Schemas[0]
+
+	c := &compiler{
+		schema: &Schema{
+			Namespace: raw.TargetNamespace,
+			Qualified: raw.ElementFormDefault == "qualified",
+			Elements:  make(map[string]*Element),
+		},
+		prefixes: make(map[string]string),
+		raw:      make(map[string]*xsdComplexType),
+		types:    make(map[string]*ComplexType),
+	}
+	for _, attrs := range [][]xml.Attr{doc.Attrs, raw.Attrs} {
+		for _, attr := range attrs {
+			switch {
+			case attr.Name.Space == "xmlns":
+				c.prefixes[attr.Name.Local] = attr.Value
+			case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+				c.prefixes[""] = attr.Value
+			}
+		}
+	}
+	for i := range raw.ComplexTypes {
+		c.raw[raw.ComplexTypes[i].Name] = &raw.ComplexTypes[i]
+	}
+
+	for i := range raw.Elements {
+		element, err := c.element(&raw.Elements[i])
+		if err != nil {
+			return nil, err
+		}
+		c.schema.Elements[element.Name] = element
+	}
+	return c.schema, nil
+}
+
+// compiler resolves type references while building a Schema
+type compiler struct {
+	schema *Schema
+	// prefixes maps namespace prefixes to namespace URIs
+	prefixes map[string]string
+	raw      map[string]*xsdComplexType
+	types    map[string]*ComplexType
+}
+
+// element compiles an element declaration
+func (c *compiler) element(raw *xsdElement) (*Element, error) {
+	if raw.Name == "" {
+		return nil, fmt.Errorf("element declarations must have a name")
+	}
+	element := &Element{Name: raw.Name}
+
+	var err error
+	if element.MinOccurs, err = occurs(raw.MinOccurs); err != nil {
+		return nil, fmt.Errorf("element %s: %w", raw.Name, err)
+	}
+	if element.MaxOccurs, err = occurs(raw.MaxOccurs); err != nil {
+		return nil, fmt.Errorf("element %s: %w", raw.Name, err)
+	}
+
+	switch {
+	case raw.ComplexType != nil:
+		element.Complex, err = c.complexType(raw.ComplexType)
+	case raw.Type != "":
+		err = c.resolveType(element, raw.Type)
+	default:
+		err = fmt.Errorf("element %s has no type", raw.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return element, nil
+}
+
+// resolveType points an element at a built-in simple type or a named complex type
+func (c *compiler) resolveType(element *Element, ref string) error {
+	prefix, local, ok := strings.Cut(ref, ":")
+	if !ok {
+		prefix, local = "", ref
+	}
+	space, ok := c.prefixes[prefix]
+	if !ok {
+		return fmt.Errorf("element %s: unknown namespace prefix in type %q", element.Name, ref)
+	}
+
+	switch space {
+	case Namespace:
+		if _, ok := builtins[local]; !ok {
+			return fmt.Errorf("element %s: unsupported built-in type %q", element.Name, ref)
+		}
+		element.Type = xml.Name{Space: space, Local: local}
+		return nil
+	case c.schema.Namespace:
+		if complex, ok := c.types[local]; ok {
+			element.Complex = complex
+			return nil
+		}
+		raw, ok := c.raw[local]
+		if !ok {
+			return fmt.Errorf("element %s: unknown type %q", element.Name, ref)
+		}
+		complex, err := c.complexType(raw)
+		if err != nil {
+			return err
+		}
+		element.Complex = complex
+		return nil
+	default:
+		return fmt.Errorf("element %s: type %q is not in the schema or XML Schema namespace", element.Name, ref)
+	}
+}
+
+// complexType compiles a named or anonymous complex type
+func (c *compiler) complexType(raw *xsdComplexType) (*ComplexType, error) {
+	complex := &ComplexType{Name: raw.Name}
+	if raw.Name != "" {
+		// Register before compiling the content so recursive types resolve
+		c.types[raw.Name] = complex
+	}
+
+	var err error
+	switch {
+	case raw.Sequence != nil:
+		complex.Content, err = c.group(raw.Sequence, false)
+	case raw.Choice != nil:
+		complex.Content, err = c.group(raw.Choice, true)
+	default:
+		complex.Content = &Group{MinOccurs: 1, MaxOccurs: 1}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return complex, nil
+}
+
+// group compiles a sequence or choice
+func (c *compiler) group(raw *xsdGroup, choice bool) (*Group, error) {
+	group := &Group{Choice: choice}
+
+	var err error
+	if group.MinOccurs, err = occurs(raw.MinOccurs); err != nil {
+		return nil, err
+	}
+	if group.MaxOccurs, err = occurs(raw.MaxOccurs); err != nil {
+		return nil, err
+	}
+	if group.MaxOccurs != 1 {
+		return nil, fmt.Errorf("repeated sequence and choice groups are not supported")
+	}
+
+	for _, item := range raw.Items {
+		var particle Particle
+		switch {
+		case item.Element != nil:
+			particle.Element, err = c.element(item.Element)
+		case item.Sequence != nil:
+			particle.Group, err = c.group(item.Sequence, false)
+		case item.Choice != nil:
+			particle.Group, err = c.group(item.Choice, true)
+		}
+		if err != nil {
+			return nil, err
+		}
+		group.Particles = append(group.Particles, particle)
+	}
+	return group, nil
+}
+
+// occurs parses a minOccurs or maxOccurs value, which defaults to 1
+func occurs(value string) (int, error) {
+	switch value {
+	case "":
+		return 1, nil
+	case "unbounded":
+		return Unbounded, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid occurrence count %q", value)
+	}
+	return n, nil
+}
@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// builtins checks the lexical space of the supported built-in simple types
+var builtins = map[string]func(value string) bool{
+	"string":   func(string) bool { return true },
+	"boolean":  validBoolean,
+	"decimal":  decimalPattern.MatchString,
+	"int":      validInt,
+	"date":     validDate,
+	"dateTime": validDateTime,
+	"anyURI":   validAnyURI,
+}
+
+// decimalPattern is the lexical space of xsd:decimal
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+
+// timezonePattern is the optional time zone of xsd:date and xsd:dateTime
+var timezonePattern = regexp.MustCompile(`(Z|[+-]\d{2}:\d{2})$`)
+
+// checkValue verifies the text of an element with a built-in simple type
+func checkValue(t string, value string) error {
+	valid := builtins[t]
+	if t != "string" {
+		// All other supported types collapse whitespace
+		value = strings.TrimSpace(value)
+	}
+	if !valid(value) {
+		return fmt.Errorf("invalid xsd:%s value %q", t, value)
+	}
+	return nil
+}
+
+func validBoolean(value string) bool {
+	switch value {
+	case "true", "false", "1", "0":
+		return true
+	}
+	return false
+}
+
+func validInt(value string) bool {
+	_, err := strconv.ParseInt(strings.TrimPrefix(value, "+"), 10, 32)
+	return err == nil && value != "+" && !strings.HasPrefix(value, "+-")
+}
+
+func validDate(value string) bool {
+	value = timezonePattern.ReplaceAllString(value, "")
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+func validDateTime(value string) bool {
+	value = timezonePattern.ReplaceAllString(value, "")
+	_, err := time.Parse("2006-01-02T15:04:05.999999999", value)
+	return err == nil
+}
+
+func validAnyURI(value string) bool {
+	_, err := url.Parse(value)
+	return err == nil
+}
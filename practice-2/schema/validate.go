@@ -0,0 +1,254 @@
+package schema
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// ValidationError describes where a document breaks the schema
+type ValidationError struct {
+	// Path locates the offending element, e.g. /ConvertCurrencyBatchRequest/item[2]/amount
+	Path string
+	// Line is the line of the offending element, or of its parent when an
+	// element is missing
+	Line    int
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s (line %d)", e.Path, e.Message, e.Line)
+}
+
+// node is an element read from a document
+type node struct {
+	name     xml.Name
+	line     int
+	children []*node
+	text     strings.Builder
+}
+
+// Decoder is the source of the tokens Validate reads, such as an
+// *xml.Decoder; errors are located by its input position
+type Decoder interface {
+	Token() (xml.Token, error)
+	InputPos() (line, column int)
+}
+
+// Validate reads the element that starts with start from the decoder and
+// checks it against the global element declaration of the same name:
+// namespaces, required and repeated elements, element order, unexpected
+// elements or text, and the values of simple types.
+func (s *Schema) Validate(decoder Decoder, start xml.StartElement) error {
+	line, _ := decoder.InputPos()
+	root, err := readNode(decoder, start, line)
+	if err != nil {
+		return err
+	}
+
+	path := "/" + start.Name.Local
+	declaration, ok := s.Elements[start.Name.Local]
+	if !ok || start.Name.Space != s.Namespace {
+		return &ValidationError{Path: path, Line: root.line,
+			Message: fmt.Sprintf("unknown element {%s}%s", start.Name.Space, start.Name.Local)}
+	}
+	return s.checkElement(declaration, root, path)
+}
+
+// readNode reads the rest of an element into a tree
+func readNode(decoder Decoder, start xml.StartElement, line int) (*node, error) {
+	n := &node{name: start.Name, line: line}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			line, _ := decoder.InputPos()
+			child, err := readNode(decoder, t, line)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		case xml.CharData:
+			n.text.Write(t)
+		case xml.EndElement:
+			return n, nil
+		}
+	}
+}
+
+// localNamespace is the namespace local elements must be in
+func (s *Schema) localNamespace() string {
+	if s.Qualified {
+		return s.Namespace
+	}
+	return ""
+}
+
+// checkElement validates an element against its declaration
+func (s *Schema) checkElement(declaration *Element, n *node, path string) error {
+	if declaration.Complex == nil {
+		if len(n.children) > 0 {
+			return &ValidationError{Path: path + "/" + n.children[0].name.Local, Line: n.children[0].line,
+				Message: fmt.Sprintf("unexpected element, %s has simple content", declaration.Name)}
+		}
+		if err := checkValue(declaration.Type.Local, n.text.String()); err != nil {
+			return &ValidationError{Path: path, Line: n.line, Message: err.Error()}
+		}
+		return nil
+	}
+
+	if text := strings.TrimSpace(n.text.String()); text != "" {
+		return &ValidationError{Path: path, Line: n.line, Message: "unexpected text content"}
+	}
+
+	m := &matcher{schema: s, parent: n, path: path, counts: make(map[string]int)}
+	if err := m.group(declaration.Complex.Content, true); err != nil {
+		return err
+	}
+	if m.next < len(n.children) {
+		return m.unexpected(n.children[m.next])
+	}
+	return nil
+}
+
+// matcher walks the children of an element through its content model
+type matcher struct {
+	schema *Schema
+	parent *node
+	path   string
+	// next is the index of the next child to match
+	next int
+	// counts numbers repeated elements for their paths
+	counts map[string]int
+}
+
+// group matches a sequence or choice. required is false inside an optional group.
+func (m *matcher) group(g *Group, required bool) error {
+	required = required && g.MinOccurs > 0
+
+	if g.Choice {
+		for _, p := range g.Particles {
+			if m.startsWith(p) {
+				return m.particle(p, required)
+			}
+		}
+		if required && m.next < len(m.parent.children) {
+			return m.missing(choiceNames(g))
+		}
+		if required {
+			return &ValidationError{Path: m.path, Line: m.parent.line,
+				Message: fmt.Sprintf("required element is missing, expected %s", choiceNames(g))}
+		}
+		return nil
+	}
+
+	for _, p := range g.Particles {
+		if err := m.particle(p, required); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// particle matches an element or nested group
+func (m *matcher) particle(p Particle, required bool) error {
+	if p.Group != nil {
+		return m.group(p.Group, required)
+	}
+
+	e := p.Element
+	count := 0
+	for m.next < len(m.parent.children) && (e.MaxOccurs == Unbounded || count < e.MaxOccurs) {
+		child := m.parent.children[m.next]
+		if child.name.Local != e.Name {
+			break
+		}
+		if err := m.checkNamespace(child); err != nil {
+			return err
+		}
+
+		path := m.path + "/" + e.Name
+		if e.MaxOccurs == Unbounded || e.MaxOccurs > 1 {
+			m.counts[e.Name]++
+			path = fmt.Sprintf("%s[%d]", path, m.counts[e.Name])
+		}
+		if err := m.schema.checkElement(e, child, path); err != nil {
+			return err
+		}
+		m.next++
+		count++
+	}
+
+	if required && count < e.MinOccurs {
+		return m.missing(e.Name)
+	}
+	return nil
+}
+
+// startsWith reports whether the next child can start a particle
+func (m *matcher) startsWith(p Particle) bool {
+	if m.next >= len(m.parent.children) {
+		return false
+	}
+	name := m.parent.children[m.next].name.Local
+	if p.Element != nil {
+		return p.Element.Name == name
+	}
+	for _, q := range p.Group.Particles {
+		if m.startsWith(q) {
+			return true
+		}
+		if !p.Group.Choice && q.Element != nil && q.Element.MinOccurs > 0 {
+			return false
+		}
+	}
+	return false
+}
+
+// checkNamespace verifies that a child element is in the namespace of local elements
+func (m *matcher) checkNamespace(child *node) error {
+	if want := m.schema.localNamespace(); child.name.Space != want {
+		message := fmt.Sprintf("element must be in namespace %q", want)
+		if want == "" {
+			message = "element must not be in a namespace"
+		}
+		return &ValidationError{Path: m.path + "/" + child.name.Local, Line: child.line, Message: message}
+	}
+	return nil
+}
+
+// missing reports a required element that is absent. If the next child is
+// not the expected element, it is reported as the cause instead.
+func (m *matcher) missing(name string) error {
+	if m.next < len(m.parent.children) {
+		child := m.parent.children[m.next]
+		for _, later := range m.parent.children[m.next:] {
+			if later.name.Local == name {
+				return &ValidationError{Path: m.path + "/" + child.name.Local, Line: child.line,
+					Message: fmt.Sprintf("element is out of order, expected %s", name)}
+			}
+		}
+		return &ValidationError{Path: m.path + "/" + child.name.Local, Line: child.line,
+			Message: fmt.Sprintf("unexpected element, expected %s", name)}
+	}
+	return &ValidationError{Path: m.path + "/" + name, Line: m.parent.line, Message: "required element is missing"}
+}
+
+// unexpected reports a child left over after the content model is complete
+func (m *matcher) unexpected(child *node) error {
+	return &ValidationError{Path: m.path + "/" + child.name.Local, Line: child.line, Message: "unexpected element"}
+}
+
+// choiceNames lists the alternatives of a choice for error messages
+func choiceNames(g *Group) string {
+	var names []string
+	for _, p := range g.Particles {
+		if p.Element != nil {
+			names = append(names, p.Element.Name)
+		}
+	}
+	return strings.Join(names, " or ")
+}
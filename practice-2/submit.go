@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"practice-2/currency"
+	"practice-2/dispatch"
+	"practice-2/jobs"
+	"practice-2/wsaddressing"
+	"pricing"
+	"soapfault"
+	"soapheader"
+)
+
+// convertCurrencyResponseAction is the wsa:Action of ConvertCurrency replies
+const convertCurrencyResponseAction = "http://practice-2/soap/ConvertCurrencyResponse"
+
+// callbackPolicy restricts the addresses the results of submitted
+// conversions are delivered to, so that clients cannot make the service send
+// requests into its own network
+type callbackPolicy struct {
+	// hosts are the host names callbacks may use; any host when empty
+	hosts map[string]bool
+	// allowPrivate permits loopback, private and link-local addresses
+	allowPrivate bool
+	// client delivers the results; unless private addresses are allowed it
+	// only connects to public IP addresses
+	client *http.Client
+}
+
+// errPrivateCallback is returned when a callback resolves to an address that
+// is not public
+var errPrivateCallback = errors.New("callback address is not public")
+
+// nonPublicPrefixes are the ranges that are global unicast in netip terms
+// but not reachable on the internet, e.g. carrier-grade NAT
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// SetCallbackPolicy restricts the callbacks of submitted conversions to the
+// given host names, or to any host if there are none. Callbacks only reach
+// public IP addresses unless allowPrivate is set.
+func (s *CurrencyService) SetCallbackPolicy(hosts []string, allowPrivate bool) {
+	s.callbacks = newCallbackPolicy(hosts, allowPrivate)
+}
+
+// newCallbackPolicy returns the policy of SetCallbackPolicy
+func newCallbackPolicy(hosts []string, allowPrivate bool) callbackPolicy {
+	policy := callbackPolicy{hosts: make(map[string]bool), allowPrivate: allowPrivate}
+	for _, host := range hosts {
+		policy.hosts[strings.ToLower(host)] = true
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		// Checking the address being dialled, rather than the URL, also
+		// covers host names that resolve to private addresses
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if !isPublic(ip) {
+				return fmt.Errorf("%w: %s", errPrivateCallback, ip)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect to the callback on the service's behalf, out of
+	// reach of the dialer's check
+	transport.Proxy = nil
+	policy.client = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
+		// A redirect could point anywhere; it is reported as the answer
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return policy
+}
+
+// isPublic tells whether an IP address is reachable on the internet, i.e.
+// not loopback, private, link-local (such as cloud metadata services),
+// multicast or unspecified
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// check fails for addresses that are not HTTP URLs of an allowed host, or
+// whose host is an IP address that is not public
+func (p callbackPolicy) check(address string) error {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s is not an HTTP URL", address)
+	}
+	host := strings.ToLower(u.Hostname())
+	if len(p.hosts) > 0 && !p.hosts[host] {
+		return fmt.Errorf("host %s is not allowed", host)
+	}
+	// Host names are checked once they are resolved, see newCallbackPolicy
+	if ip, err := netip.ParseAddr(host); err == nil && !p.allowPrivate && !isPublic(ip) {
+		return fmt.Errorf("%w: %s", errPrivateCallback, ip)
+	}
+	return nil
+}
+
+// conversionJob is the payload of the job of a SubmitConversion request
+type conversionJob struct {
+	// Request is the ConvertCurrencyRequest in XML, the form the gowsdl
+	// types are made for
+	Request string `json:"request"`
+	ReplyTo string `json:"replyTo"`
+	// FaultTo receives faults instead of ReplyTo when set
+	FaultTo       string `json:"faultTo,omitempty"`
+	MessageID     string `json:"messageId,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+
+	// Message is the SOAP message with the result, kept after the first
+	// attempt so that retries deliver the same result to Destination
+	Message     string `json:"message,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Action      string `json:"action,omitempty"`
+}
+
+// SubmitConversion queues a conversion whose result is delivered to a callback
+func (s *CurrencyService) SubmitConversion(request *currency.SubmitConversionRequest) error {
+	return s.SubmitConversionContext(context.Background(), request)
+}
+
+// SubmitConversionContext checks the callback and the currencies of a
+// conversion and queues it. The ConvertCurrencyResponse, or a fault, is
+// delivered to callbackURL or to the wsa:ReplyTo address of the request;
+// faults go to its wsa:FaultTo address if it has one.
+func (s *CurrencyService) SubmitConversionContext(ctx context.Context, request *currency.SubmitConversionRequest) error {
+	job := conversionJob{}
+	var err error
+	if job.ReplyTo, job.FaultTo, err = s.callbackAddresses(ctx, string(request.CallbackURL)); err != nil {
+		return soapfault.New(soapfault.Client, soapfault.InvalidCallback, "Invalid callback", err)
+	}
+	if properties, ok := wsaddressing.FromContext(ctx); ok {
+		job.MessageID = properties.MessageID
+	}
+	job.CorrelationID, _ = soapheader.CorrelationIDFromContext(ctx)
+
+	// Requests that cannot succeed are rejected now rather than at the callback
+	if err := pricing.CheckAmount(request.Amount); err != nil {
+		return err
+	}
+	if _, err := s.currencies.Validate(request.FromCurrency); err != nil {
+		return fmt.Errorf("fromCurrency: %w", err)
+	}
+	if _, err := s.currencies.Validate(request.ToCurrency); err != nil {
+		return fmt.Errorf("toCurrency: %w", err)
+	}
+
+	conversion, err := xml.Marshal(&currency.ConvertCurrencyRequest{
+		Amount:       request.Amount,
+		FromCurrency: request.FromCurrency,
+		ToCurrency:   request.ToCurrency,
+		ValueDate:    request.ValueDate,
+	})
+	if err != nil {
+		return err
+	}
+	job.Request = string(conversion)
+
+	_, err = s.jobs.Submit(job)
+	return err
+}
+
+// callbackAddresses returns the addresses the result of a submitted
+// conversion is delivered to: callbackURL or the wsa:ReplyTo address, which
+// must agree if both are given, and the wsa:FaultTo address, if any. Both
+// must be allowed by the callback policy.
+func (s *CurrencyService) callbackAddresses(ctx context.Context, callbackURL string) (replyTo, faultTo string, err error) {
+	replyTo = strings.TrimSpace(callbackURL)
+	if properties, ok := wsaddressing.FromContext(ctx); ok {
+		if endpoint := properties.ReplyTo; endpoint != nil && endpoint.Address != wsaddressing.Anonymous {
+			if replyTo != "" && replyTo != endpoint.Address {
+				return "", "", fmt.Errorf("callbackURL %s differs from wsa:ReplyTo %s", replyTo, endpoint.Address)
+			}
+			replyTo = endpoint.Address
+		}
+		if endpoint := properties.FaultTo; endpoint != nil && endpoint.Address != wsaddressing.Anonymous {
+			faultTo = endpoint.Address
+		}
+	}
+	if replyTo == "" {
+		return "", "", fmt.Errorf("a callbackURL or wsa:ReplyTo address is required")
+	}
+	if err := s.callbacks.check(replyTo); err != nil {
+		return "", "", fmt.Errorf("callbackURL: %w", err)
+	}
+	if faultTo != "" {
+		if err := s.callbacks.check(faultTo); err != nil {
+			return "", "", fmt.Errorf("wsa:FaultTo: %w", err)
+		}
+	}
+	return replyTo, faultTo, nil
+}
+
+// DeliverConversion is the jobs.Handler of submitted conversions. The first
+// attempt converts the amount; every attempt posts the result to the callback.
+func (s *CurrencyService) DeliverConversion(ctx context.Context, job *jobs.Job) error {
+	var payload conversionJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	if payload.Message == "" {
+		if err := s.prepareDelivery(ctx, &payload); err != nil {
+			return err
+		}
+		if err := job.SetPayload(payload); err != nil {
+			return jobs.Permanent(err)
+		}
+	}
+	return s.deliver(ctx, payload.Destination, payload.Action, payload.Message)
+}
+
+// prepareDelivery converts the amount of a job and sets the message with
+// the result and the address and action it is delivered with
+func (s *CurrencyService) prepareDelivery(ctx context.Context, payload *conversionJob) error {
+	request := new(currency.ConvertCurrencyRequest)
+	if err := xml.Unmarshal([]byte(payload.Request), request); err != nil {
+		return jobs.Permanent(err)
+	}
+
+	var body dispatch.Body
+	response, err := s.ConvertCurrencyContext(ctx, request)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if err != nil {
+		body.Fault = faultFor(err).SOAP11()
+		payload.Destination, payload.Action = payload.ReplyTo, wsaddressing.FaultAction
+		if payload.FaultTo != "" {
+			payload.Destination = payload.FaultTo
+		}
+	} else {
+		body.Content = response
+		payload.Destination, payload.Action = payload.ReplyTo, convertCurrencyResponseAction
+	}
+
+	entries := wsaddressing.Headers(payload.Destination, payload.Action, payload.MessageID)
+	if payload.CorrelationID != "" {
+		entries = append(entries, &soapheader.CorrelationID{Value: payload.CorrelationID})
+	}
+	output, err := xml.MarshalIndent(dispatch.Envelope{Header: &dispatch.Header{Entries: entries}, Body: body}, "", "  ")
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	payload.Message = xml.Header + string(output)
+	return nil
+}
+
+// deliver posts a SOAP message to a callback. Callbacks that cannot be
+// reached or answer with a server error, 408 or 429 are tried again; any
+// other client error or a redirect fails the job, as does a callback the
+// policy does not allow.
+func (s *CurrencyService) deliver(ctx context.Context, destination, action, message string) error {
+	// The policy may have changed since the job was submitted
+	if err := s.callbacks.check(destination); err != nil {
+		return jobs.Permanent(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, destination, strings.NewReader(message))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", `"`+action+`"`)
+
+	resp, err := s.callbacks.client.Do(req)
+	if errors.Is(err, errPrivateCallback) {
+		return jobs.Permanent(err)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("callback %s answered %s", destination, resp.Status)
+	default:
+		return jobs.Permanent(fmt.Errorf("callback %s answered %s", destination, resp.Status))
+	}
+}
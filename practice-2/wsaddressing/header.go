@@ -0,0 +1,49 @@
+// Package wsaddressing reads the WS-Addressing 1.0 headers of SOAP requests
+// and writes those of the replies. Register adds the handlers of the
+// message addressing properties to a soapheader.Registry; operation handlers
+// find the properties of their request with FromContext.
+//
+// Replies of request-response operations are sent in the HTTP response, so
+// wsa:ReplyTo and wsa:FaultTo may only name the anonymous address, see
+// RequireAnonymous; one-way operations may have them name HTTP URLs.
+package wsaddressing
+
+import "encoding/xml"
+
+// Namespace is the WS-Addressing 1.0 namespace
+const Namespace = "http://www.w3.org/2005/08/addressing"
+
+// Anonymous is the address of the back channel, the HTTP response
+const Anonymous = Namespace + "/anonymous"
+
+// FaultAction is the action of fault replies
+const FaultAction = Namespace + "/fault"
+
+// Names of the header entries of the message addressing properties
+var (
+	ToName        = xml.Name{Space: Namespace, Local: "To"}
+	ActionName    = xml.Name{Space: Namespace, Local: "Action"}
+	MessageIDName = xml.Name{Space: Namespace, Local: "MessageID"}
+	RelatesToName = xml.Name{Space: Namespace, Local: "RelatesTo"}
+	ReplyToName   = xml.Name{Space: Namespace, Local: "ReplyTo"}
+	FaultToName   = xml.Name{Space: Namespace, Local: "FaultTo"}
+)
+
+// EndpointReference is the value of the wsa:ReplyTo and wsa:FaultTo headers
+type EndpointReference struct {
+	Address string `xml:"http://www.w3.org/2005/08/addressing Address"`
+}
+
+// RelatesTo is a wsa:RelatesTo header, naming a message by its wsa:MessageID
+type RelatesTo struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/08/addressing RelatesTo"`
+	// RelationshipType is Reply when empty
+	RelationshipType string `xml:"RelationshipType,attr,omitempty"`
+	Value            string `xml:",chardata"`
+}
+
+// uri is a header whose value is an IRI, e.g. wsa:Action
+type uri struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
@@ -0,0 +1,238 @@
+package wsaddressing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"soapfault"
+	"soapheader"
+)
+
+// Fault codes of the WS-Addressing SOAP binding
+var (
+	InvalidAddressingHeaderCode         = &soapfault.Subcode{Namespace: Namespace, Prefix: "wsa", Local: "InvalidAddressingHeader"}
+	MessageAddressingHeaderRequiredCode = &soapfault.Subcode{Namespace: Namespace, Prefix: "wsa", Local: "MessageAddressingHeaderRequired"}
+	ActionMismatchCode                  = &soapfault.Subcode{Namespace: Namespace, Prefix: "wsa", Local: "ActionMismatch"}
+	ActionNotSupportedCode              = &soapfault.Subcode{Namespace: Namespace, Prefix: "wsa", Local: "ActionNotSupported"}
+)
+
+// Properties are the message addressing properties of a request
+type Properties struct {
+	To        string
+	Action    string
+	MessageID string
+	// RelatesTo is set when the request is itself a reply
+	RelatesTo *RelatesTo
+	// ReplyTo and FaultTo are nil when the request has no such header,
+	// which means the anonymous address
+	ReplyTo *EndpointReference
+	FaultTo *EndpointReference
+}
+
+type propertiesKey struct{}
+
+// FromContext returns the message addressing properties of a request, if
+// it has any WS-Addressing headers
+func FromContext(ctx context.Context) (*Properties, bool) {
+	properties, ok := ctx.Value(propertiesKey{}).(*Properties)
+	return properties, ok
+}
+
+// Register adds the handlers of the WS-Addressing headers to a registry
+func Register(r *soapheader.Registry) {
+	r.Handle(ToName, handle(func(p *Properties, entry *soapheader.Entry) (err error) {
+		p.To, err = decodeURI(entry)
+		return err
+	}))
+	r.Handle(ActionName, handle(func(p *Properties, entry *soapheader.Entry) (err error) {
+		p.Action, err = decodeURI(entry)
+		return err
+	}))
+	r.Handle(MessageIDName, handle(func(p *Properties, entry *soapheader.Entry) (err error) {
+		p.MessageID, err = decodeURI(entry)
+		return err
+	}))
+	r.Handle(RelatesToName, handle(func(p *Properties, entry *soapheader.Entry) error {
+		p.RelatesTo = new(RelatesTo)
+		if err := entry.Decode(p.RelatesTo); err != nil {
+			return invalid("wsa:RelatesTo: %v", err)
+		}
+		if p.RelatesTo.Value = strings.TrimSpace(p.RelatesTo.Value); p.RelatesTo.Value == "" {
+			return invalid("wsa:RelatesTo is empty")
+		}
+		return nil
+	}))
+	r.Handle(ReplyToName, handle(func(p *Properties, entry *soapheader.Entry) (err error) {
+		p.ReplyTo, err = decodeEndpoint(entry)
+		return err
+	}))
+	r.Handle(FaultToName, handle(func(p *Properties, entry *soapheader.Entry) (err error) {
+		p.FaultTo, err = decodeEndpoint(entry)
+		return err
+	}))
+}
+
+// handle returns the handler of a header, which sets one of the properties
+// kept in the context of the request
+func handle(set func(p *Properties, entry *soapheader.Entry) error) soapheader.Handler {
+	return func(ctx context.Context, entry *soapheader.Entry) (context.Context, error) {
+		properties, ok := FromContext(ctx)
+		if !ok {
+			properties = new(Properties)
+			ctx = context.WithValue(ctx, propertiesKey{}, properties)
+		}
+		return ctx, set(properties, entry)
+	}
+}
+
+// decodeURI reads a header whose value is an IRI
+func decodeURI(entry *soapheader.Entry) (string, error) {
+	var header uri
+	if err := entry.Decode(&header); err != nil {
+		return "", invalid("wsa:%s: %v", entry.Name.Local, err)
+	}
+	value := strings.TrimSpace(header.Value)
+	if value == "" || strings.ContainsAny(value, " \t\r\n") {
+		return "", invalid("wsa:%s is not an IRI", entry.Name.Local)
+	}
+	return value, nil
+}
+
+// decodeEndpoint reads an endpoint reference, which must be the anonymous
+// one or an HTTP URL
+func decodeEndpoint(entry *soapheader.Entry) (*EndpointReference, error) {
+	endpoint := new(EndpointReference)
+	if err := entry.Decode(endpoint); err != nil {
+		return nil, invalid("wsa:%s: %v", entry.Name.Local, err)
+	}
+	endpoint.Address = strings.TrimSpace(endpoint.Address)
+	if endpoint.Address == "" {
+		return nil, invalid("wsa:%s has no wsa:Address", entry.Name.Local)
+	}
+	if endpoint.Address != Anonymous {
+		u, err := url.Parse(endpoint.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, invalid("wsa:%s must be the anonymous address or an HTTP URL, got %s", entry.Name.Local, endpoint.Address)
+		}
+	}
+	return endpoint, nil
+}
+
+// RequireAnonymous fails for requests whose wsa:ReplyTo or wsa:FaultTo is
+// not the anonymous address, as the reply of a request-response operation
+// is sent in the HTTP response
+func RequireAnonymous(ctx context.Context) error {
+	properties, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	if endpoint := properties.ReplyTo; endpoint != nil && endpoint.Address != Anonymous {
+		return invalid("wsa:ReplyTo must be the anonymous address %s, got %s", Anonymous, endpoint.Address)
+	}
+	if endpoint := properties.FaultTo; endpoint != nil && endpoint.Address != Anonymous {
+		return invalid("wsa:FaultTo must be the anonymous address %s, got %s", Anonymous, endpoint.Address)
+	}
+	return nil
+}
+
+// RequireMessageID fails for requests with WS-Addressing headers but no
+// wsa:MessageID, which the reply of a request-response operation relates to.
+// Messages of one-way operations need none.
+func RequireMessageID(ctx context.Context) error {
+	properties, ok := FromContext(ctx)
+	if ok && properties.MessageID == "" {
+		return required("missing wsa:MessageID")
+	}
+	return nil
+}
+
+// RequestAction returns the action a request is routed by: its wsa:Action,
+// or the SOAPAction HTTP header if it has no WS-Addressing headers. A request
+// with WS-Addressing headers must have a wsa:Action; a non-empty SOAPAction
+// must equal it. Whether the request needs a wsa:MessageID depends on its
+// operation, see RequireMessageID.
+func RequestAction(ctx context.Context, soapAction string) (string, error) {
+	properties, ok := FromContext(ctx)
+	if !ok {
+		return soapAction, nil
+	}
+	if properties.Action == "" {
+		return "", required("missing wsa:Action")
+	}
+	if soapAction != "" && soapAction != properties.Action {
+		fault := soapfault.New(soapfault.Client, soapfault.ActionMismatch,
+			"The SOAPAction and the wsa:Action of the message do not match",
+			fmt.Errorf("SOAPAction %q differs from wsa:Action %q", soapAction, properties.Action))
+		fault.Subcode = ActionMismatchCode
+		return "", fault
+	}
+	return properties.Action, nil
+}
+
+// ActionNotSupported returns the fault for a wsa:Action no operation has
+func ActionNotSupported(action string) *soapfault.Error {
+	fault := soapfault.New(soapfault.Client, soapfault.ActionNotSupported,
+		"The action cannot be processed at the receiver", fmt.Errorf("unknown wsa:Action %q", action))
+	fault.Subcode = ActionNotSupportedCode
+	return fault
+}
+
+// ReplyHeaders returns the WS-Addressing headers of the reply to the
+// request of ctx, with the given action, or nil if the request has none
+func ReplyHeaders(ctx context.Context, action string) []interface{} {
+	properties, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return Headers("", action, properties.MessageID)
+}
+
+// Headers returns the WS-Addressing headers of a message sent to the
+// address to, with the given action, in reply to the message relatesTo.
+// The message gets a new wsa:MessageID; to and relatesTo may be empty.
+func Headers(to, action, relatesTo string) []interface{} {
+	var entries []interface{}
+	if to != "" {
+		entries = append(entries, &uri{XMLName: ToName, Value: to})
+	}
+	entries = append(entries, &uri{XMLName: ActionName, Value: action})
+	if id, err := newMessageID(); err == nil {
+		entries = append(entries, &uri{XMLName: MessageIDName, Value: id})
+	}
+	if relatesTo != "" {
+		entries = append(entries, &RelatesTo{Value: relatesTo})
+	}
+	return entries
+}
+
+// newMessageID returns a random UUID URN
+func newMessageID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// invalid returns a fault for a WS-Addressing header that cannot be processed
+func invalid(format string, args ...interface{}) *soapfault.Error {
+	fault := soapfault.New(soapfault.Client, soapfault.InvalidAddressing,
+		"A header representing a Message Addressing Property is not valid and the message cannot be processed",
+		fmt.Errorf(format, args...))
+	fault.Subcode = InvalidAddressingHeaderCode
+	return fault
+}
+
+// required returns a fault for a missing WS-Addressing header
+func required(format string, args ...interface{}) *soapfault.Error {
+	fault := soapfault.New(soapfault.Client, soapfault.AddressingRequired,
+		"A required header representing a Message Addressing Property is not present",
+		fmt.Errorf(format, args...))
+	fault.Subcode = MessageAddressingHeaderRequiredCode
+	return fault
+}
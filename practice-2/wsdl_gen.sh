@@ -1 +1,12 @@
-gowsdl -p currency -o currency_gen.go ./wsdl/currency.wsdl
\ No newline at end of file
+gowsdl -p currency -o currency_gen.go ./wsdl/currency.wsdl
+
+# gowsdl maps xsd:decimal to float64; use the exact currency.Decimal type instead
+sed -i 's/ float64 `/ Decimal `/' currency/currency_gen.go
+
+# gowsdl tags every field omitempty; required elements whose value can be zero
+# must still be sent
+sed -i 's/`xml:"minorUnits,omitempty" json:"minorUnits,omitempty"`/`xml:"minorUnits" json:"minorUnits"`/' currency/currency_gen.go
+sed -i 's/`xml:"active,omitempty" json:"active,omitempty"`/`xml:"active" json:"active"`/' currency/currency_gen.go
+
+# The server side: registers the CurrencyConversionPortType operations with a dispatcher
+go run ./cmd/soapgen -p currency -o currency_server_gen.go ./wsdl/currency.wsdl
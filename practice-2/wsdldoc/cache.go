@@ -0,0 +1,150 @@
+package wsdldoc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VersionHeader carries a hash of the document file, independent of the
+// addresses rendered into it, so clients can tell when the service
+// description has changed
+const VersionHeader = "X-Document-Version"
+
+// maxRenderings limits the number of cached renderings. Each base URL a
+// document is requested with needs one, and with forwarded headers the
+// client chooses it; further renderings are made for every request.
+const maxRenderings = 64
+
+// document is a WSDL or XSD file read into memory
+type document struct {
+	name    string
+	data    []byte
+	modTime time.Time
+	version string
+}
+
+// rendering is a document with its addresses rewritten for one base URL
+type rendering struct {
+	identity []byte
+	gzipped  []byte
+	etag     string
+	gzipETag string
+}
+
+type renderingKey struct {
+	name string
+	base string
+}
+
+// readDocuments reads the .wsdl and .xsd files of a directory, checking
+// that each of them can be rendered
+func readDocuments(dir string) (map[string]*document, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make(map[string]*document)
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".wsdl", ".xsd":
+		default:
+			continue
+		}
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := Render(data, "http://localhost/", "http://localhost/"+entry.Name()); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		sum := sha256.Sum256(data)
+		documents[entry.Name()] = &document{
+			name:    entry.Name(),
+			data:    data,
+			modTime: info.ModTime().UTC().Truncate(time.Second),
+			version: hex.EncodeToString(sum[:8]),
+		}
+	}
+	if len(documents) == 0 {
+		return nil, fmt.Errorf("no WSDL or XSD documents in %s", dir)
+	}
+	return documents, nil
+}
+
+// render returns the rendering of a document for a base URL, from the cache
+// when possible
+func (s *Server) render(doc *document, base string) (*rendering, error) {
+	key := renderingKey{name: doc.name, base: base}
+	s.mu.Lock()
+	cached, ok := s.renderings[key]
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	identity, err := Render(doc.data, base+s.endpoint, base+s.prefix+doc.name)
+	if err != nil {
+		return nil, err
+	}
+	var gzipped bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&gzipped, gzip.BestCompression)
+	zw.Write(identity)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(identity)
+	hash := hex.EncodeToString(sum[:16])
+	rendered := &rendering{
+		identity: identity,
+		gzipped:  gzipped.Bytes(),
+		etag:     `"` + hash + `"`,
+		gzipETag: `"` + hash + `-gzip"`,
+	}
+
+	s.mu.Lock()
+	if len(s.renderings) < maxRenderings {
+		s.renderings[key] = rendered
+	}
+	s.mu.Unlock()
+	return rendered, nil
+}
+
+// acceptsGzip reports whether the Accept-Encoding of a request allows gzip
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+				continue
+			}
+			// gzip;q=0 refuses the coding
+			if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+				q, err := strconv.ParseFloat(value, 64)
+				return err == nil && q > 0
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+// Package wsdldoc serves the WSDL and XSD documents of the service. The
+// soap:address of each port and the locations of imported documents are
+// rewritten to the address the client reached the server at, so the
+// documents stay correct behind proxies.
+package wsdldoc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Namespaces of the elements whose addresses are rewritten
+const (
+	wsdlNamespace   = "http://schemas.xmlsoap.org/wsdl/"
+	soapNamespace   = "http://schemas.xmlsoap.org/wsdl/soap/"
+	soap12Namespace = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	xsdNamespace    = "http://www.w3.org/2001/XMLSchema"
+)
+
+// Server serves the .wsdl and .xsd files of a directory. The files are read
+// once, when the server is created; each rendering of a document is kept in
+// memory together with its gzip encoding and ETag.
+type Server struct {
+	// prefix is the URL path the documents are served under, e.g. /wsdl/
+	prefix string
+	// endpoint is the URL path of the SOAP endpoint, e.g. /soap/convert-currency
+	endpoint string
+	// documents are the files of the directory by name
+	documents map[string]*document
+
+	mu sync.Mutex
+	// renderings caches rendered documents by name and base URL
+	renderings map[renderingKey]*rendering
+
+	// BaseURL, when set, is the public URL of the server, e.g.
+	// https://api.example.com/currency, and is used instead of the address
+	// derived from the request
+	BaseURL string
+	// TrustForwarded derives the address from the X-Forwarded-Proto,
+	// X-Forwarded-Host and X-Forwarded-Prefix headers. Only enable it when
+	// a proxy that sets them is in front of the server.
+	TrustForwarded bool
+}
+
+// NewServer creates a server for the documents in dir, served under prefix,
+// which describe the SOAP endpoint at the given path
+func NewServer(dir, prefix, endpoint string) (*Server, error) {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	documents, err := readDocuments(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		prefix:     prefix,
+		endpoint:   endpoint,
+		documents:  documents,
+		renderings: make(map[renderingKey]*rendering),
+	}, nil
+}
+
+// ServeHTTP serves the document named by the last element of the URL path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, path.Base(r.URL.Path))
+}
+
+// WithWSDL serves the named WSDL document for GET requests to the endpoint
+// with a ?wsdl query, as many SOAP clients expect, and passes any other
+// request to next
+func (s *Server) WithWSDL(next http.Handler, name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			query := r.URL.Query()
+			if query.Has("wsdl") || query.Has("WSDL") {
+				s.serve(w, r, name)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve writes a document with its addresses rewritten for the request.
+// Conditional and range requests are answered by http.ServeContent.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	doc, ok := s.documents[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	base, err := s.baseURL(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rendered, err := s.render(doc, base)
+	if err != nil {
+		http.Error(w, "Error rendering WSDL file", http.StatusInternalServerError)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/xml; charset=utf-8")
+	// Clients may keep the document but must revalidate it, as its
+	// addresses depend on the request
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Vary", "Accept-Encoding")
+	if s.BaseURL == "" && s.TrustForwarded {
+		header.Add("Vary", "X-Forwarded-Proto, X-Forwarded-Host, X-Forwarded-Prefix")
+	}
+	header.Set(VersionHeader, doc.version)
+
+	content, etag := rendered.identity, rendered.etag
+	if acceptsGzip(r) {
+		content, etag = rendered.gzipped, rendered.gzipETag
+		header.Set("Content-Encoding", "gzip")
+	}
+	header.Set("ETag", etag)
+	http.ServeContent(w, r, name, doc.modTime, bytes.NewReader(content))
+}
+
+// baseURL returns the scheme, host and path prefix the client used to reach the server
+func (s *Server) baseURL(r *http.Request) (string, error) {
+	if s.BaseURL != "" {
+		return strings.TrimSuffix(s.BaseURL, "/"), nil
+	}
+
+	scheme, host, prefix := "http", r.Host, ""
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if s.TrustForwarded {
+		if value := forwarded(r, "X-Forwarded-Proto"); value != "" {
+			scheme = strings.ToLower(value)
+		}
+		if value := forwarded(r, "X-Forwarded-Host"); value != "" {
+			host = value
+		}
+		prefix = strings.TrimSuffix(forwarded(r, "X-Forwarded-Prefix"), "/")
+		if prefix != "" && !strings.HasPrefix(prefix, "/") {
+			prefix = "/" + prefix
+		}
+	}
+
+	if scheme != "http" && scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %q", scheme)
+	}
+	if !hostPattern.MatchString(host) {
+		return "", fmt.Errorf("invalid host %q", host)
+	}
+	base, err := url.Parse(scheme + "://" + host + prefix)
+	if err != nil || base.RawQuery != "" || base.Fragment != "" {
+		return "", fmt.Errorf("invalid path prefix %q", prefix)
+	}
+	return base.String(), nil
+}
+
+// hostPattern matches a host name or IP address with an optional port
+var hostPattern = regexp.MustCompile(`^([A-Za-z0-9.-]+|\[[0-9A-Fa-f:.]+\])(:[0-9]+)?$`)
+
+// forwarded returns the first value of a header set by proxies, which
+// append their own value to a comma separated list
+func forwarded(r *http.Request, header string) string {
+	value, _, _ := strings.Cut(r.Header.Get(header), ",")
+	return strings.TrimSpace(value)
+}
+
+// Render rewrites the addresses in a WSDL or XSD document: the location of
+// soap:address and soap12:address elements becomes endpoint, and relative
+// locations of wsdl:import, xsd:import and xsd:include elements are
+// resolved against documentURL, the URL the document is served at.
+// Everything else, including formatting and comments, is kept as is.
+func Render(data []byte, endpoint, documentURL string) ([]byte, error) {
+	base, err := url.Parse(documentURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	copied := int64(0)
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		attr, value := rewrite(element, endpoint, base)
+		if attr == "" {
+			continue
+		}
+		end := decoder.InputOffset()
+		tag, ok := replaceAttr(data[start:end], attr, value)
+		if !ok {
+			return nil, fmt.Errorf("cannot rewrite the %s attribute of %s", attr, element.Name.Local)
+		}
+		out.Write(data[copied:start])
+		out.Write(tag)
+		copied = end
+	}
+	out.Write(data[copied:])
+	return out.Bytes(), nil
+}
+
+// rewrite returns the attribute of an element to rewrite and its new value,
+// or an empty attribute name if the element is kept as is
+func rewrite(element xml.StartElement, endpoint string, base *url.URL) (string, string) {
+	var attr string
+	switch element.Name {
+	case xml.Name{Space: soapNamespace, Local: "address"}, xml.Name{Space: soap12Namespace, Local: "address"}:
+		return "location", endpoint
+	case xml.Name{Space: wsdlNamespace, Local: "import"}:
+		attr = "location"
+	case xml.Name{Space: xsdNamespace, Local: "import"}, xml.Name{Space: xsdNamespace, Local: "include"}:
+		attr = "schemaLocation"
+	default:
+		return "", ""
+	}
+
+	for _, a := range element.Attr {
+		if a.Name.Space != "" || a.Name.Local != attr {
+			continue
+		}
+		ref, err := url.Parse(a.Value)
+		if err != nil || ref.IsAbs() {
+			return "", ""
+		}
+		return attr, base.ResolveReference(ref).String()
+	}
+	return "", ""
+}
+
+// replaceAttr sets the value of an unprefixed attribute in a start tag
+func replaceAttr(tag []byte, attr, value string) ([]byte, bool) {
+	pattern := regexp.MustCompile(`(\s` + regexp.QuoteMeta(attr) + `\s*=\s*)("[^"]*"|'[^']*')`)
+	loc := pattern.FindSubmatchIndex(tag)
+	if loc == nil {
+		return nil, false
+	}
+
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(value))
+
+	var out bytes.Buffer
+	out.Write(tag[:loc[3]])
+	out.WriteByte('"')
+	out.Write(escaped.Bytes())
+	out.WriteByte('"')
+	out.Write(tag[loc[5]:])
+	return out.Bytes(), true
+}
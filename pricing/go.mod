@@ -0,0 +1,10 @@
+module pricing
+
+go 1.21
+
+require (
+	gopkg.in/yaml.v3 v3.0.1
+	money v0.0.0
+)
+
+replace money => ../money
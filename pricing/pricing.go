@@ -0,0 +1,320 @@
+// Package pricing turns mid-market exchange rates into customer prices by
+// applying bid/ask spreads and conversion fees.
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"money"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Wildcard matches any currency in a fee schedule pair, e.g. "*/USD"
+const Wildcard = "*"
+
+// percent converts a percentage into a fraction
+var percent = money.MustParse("0.01")
+
+// Spread is the margin around the mid rate of a currency pair, in percent
+// of the mid rate. For the pair "EUR/USD", Bid applies when the customer
+// sells EUR for USD and Ask when the customer buys EUR with USD.
+type Spread struct {
+	Bid money.Decimal `json:"bid" yaml:"bid"`
+	Ask money.Decimal `json:"ask" yaml:"ask"`
+}
+
+// FeeType selects how a fee is computed
+type FeeType string
+
+const (
+	// Flat charges a fixed amount
+	Flat FeeType = "flat"
+	// Percentage charges a share of the converted amount
+	Percentage FeeType = "percentage"
+	// Tiered picks a flat amount and percentage by the size of the converted amount
+	Tiered FeeType = "tiered"
+)
+
+// Fee is charged on a conversion in the target currency and deducted from
+// the converted amount
+type Fee struct {
+	Type FeeType `json:"type" yaml:"type"`
+	// Amount is the fixed fee of a flat fee
+	Amount money.Decimal `json:"amount" yaml:"amount"`
+	// Percent is the share of the converted amount charged by a percentage fee
+	Percent money.Decimal `json:"percent" yaml:"percent"`
+	// Tiers are the brackets of a tiered fee, in ascending order
+	Tiers []Tier `json:"tiers,omitempty" yaml:"tiers,omitempty"`
+	// Min and Max optionally bound the computed fee
+	Min *money.Decimal `json:"min,omitempty" yaml:"min,omitempty"`
+	Max *money.Decimal `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// Tier is a bracket of a tiered fee covering converted amounts up to UpTo
+type Tier struct {
+	// UpTo is the inclusive upper bound of the bracket; nil for the last one
+	UpTo    *money.Decimal `json:"upTo,omitempty" yaml:"upTo,omitempty"`
+	Amount  money.Decimal  `json:"amount" yaml:"amount"`
+	Percent money.Decimal  `json:"percent" yaml:"percent"`
+}
+
+// Schedule holds the spreads and fees applied to conversions.
+// The zero value prices every conversion at the mid rate without fees.
+type Schedule struct {
+	// Spreads are keyed by currency pair, e.g. "EUR/USD"
+	Spreads map[string]Spread `json:"spreads,omitempty" yaml:"spreads,omitempty"`
+	// DefaultSpread applies on both sides of pairs without a spread of their own
+	DefaultSpread money.Decimal `json:"defaultSpread" yaml:"defaultSpread"`
+	// Fees are keyed by currency pair; either side may be the wildcard "*".
+	// The most specific match wins: "EUR/USD", "*/USD", "EUR/*", then "*/*".
+	Fees map[string]Fee `json:"fees,omitempty" yaml:"fees,omitempty"`
+}
+
+// Price is the breakdown of a priced conversion
+type Price struct {
+	// MidRate is the market rate before the spread
+	MidRate money.Decimal
+	// AppliedRate is the rate the customer gets after the spread
+	AppliedRate money.Decimal
+	// ConvertedAmount is the amount at the applied rate, before the fee
+	ConvertedAmount money.Decimal
+	// Fee is charged in the target currency
+	Fee money.Decimal
+	// NetAmount is the converted amount less the fee
+	NetAmount money.Decimal
+}
+
+// InvalidAmountError is returned for amounts that are zero or negative
+type InvalidAmountError struct {
+	Amount money.Decimal
+}
+
+func (e *InvalidAmountError) Error() string {
+	return fmt.Sprintf("amount must be positive, got %s", e.Amount)
+}
+
+// CheckAmount returns an *InvalidAmountError unless amount is positive.
+// Price and PriceAt check it themselves; it lets callers reject amounts
+// before they price them.
+func CheckAmount(amount money.Decimal) error {
+	if amount.Sign() <= 0 {
+		return &InvalidAmountError{Amount: amount}
+	}
+	return nil
+}
+
+// FeeExceedsAmountError is returned when the fee would use up the whole
+// converted amount
+type FeeExceedsAmountError struct {
+	Fee    money.Decimal
+	Amount money.Decimal
+}
+
+func (e *FeeExceedsAmountError) Error() string {
+	return fmt.Sprintf("fee %s exceeds the converted amount %s", e.Fee, e.Amount)
+}
+
+// Pair returns the schedule key of a currency pair
+func Pair(from, to string) string {
+	return from + "/" + to
+}
+
+// Rate applies the spread for converting from one currency to another to
+// the mid rate. The result is rounded to scale fraction digits in favour
+// of the service, so the customer is never quoted more than the spread allows.
+func (s *Schedule) Rate(mid money.Decimal, from, to string, scale int) money.Decimal {
+	if from == to {
+		return mid
+	}
+
+	var applied money.Decimal
+	if spread, ok := s.Spreads[Pair(from, to)]; ok {
+		// The customer sells the base currency of the pair
+		applied = mid.Mul(money.NewFromInt(1).Sub(spread.Bid.Mul(percent)))
+	} else if spread, ok := s.Spreads[Pair(to, from)]; ok {
+		// The customer buys the base currency of the pair
+		applied, _ = mid.Div(money.NewFromInt(1).Add(spread.Ask.Mul(percent)))
+	} else {
+		applied = mid.Mul(money.NewFromInt(1).Sub(s.DefaultSpread.Mul(percent)))
+	}
+	return applied.Round(scale, money.Floor).Normalize()
+}
+
+// Fee returns the fee for a conversion producing the given converted
+// amount, rounded to places fraction digits
+func (s *Schedule) Fee(from, to string, converted money.Decimal, places int, mode money.RoundingMode) money.Decimal {
+	fee, ok := s.fee(from, to)
+	if !ok {
+		return money.NewFromInt(0).Round(places, mode)
+	}
+
+	var charge money.Decimal
+	switch fee.Type {
+	case Flat:
+		charge = fee.Amount
+	case Percentage:
+		charge = converted.Mul(fee.Percent.Mul(percent))
+	case Tiered:
+		for _, tier := range fee.Tiers {
+			if tier.UpTo == nil || converted.Cmp(*tier.UpTo) <= 0 {
+				charge = tier.Amount.Add(converted.Mul(tier.Percent.Mul(percent)))
+				break
+			}
+		}
+	}
+
+	if fee.Min != nil && charge.Cmp(*fee.Min) < 0 {
+		charge = *fee.Min
+	}
+	if fee.Max != nil && charge.Cmp(*fee.Max) > 0 {
+		charge = *fee.Max
+	}
+	return charge.Round(places, mode)
+}
+
+// fee finds the most specific fee rule for a pair
+func (s *Schedule) fee(from, to string) (Fee, bool) {
+	for _, key := range []string{Pair(from, to), Pair(Wildcard, to), Pair(from, Wildcard), Pair(Wildcard, Wildcard)} {
+		if fee, ok := s.Fees[key]; ok {
+			return fee, true
+		}
+	}
+	return Fee{}, false
+}
+
+// Price converts amount at the mid rate after applying the spread and fee.
+// The amount must be positive. Amounts are rounded to places fraction digits of the target currency and
+// the applied rate to scale fraction digits.
+func (s *Schedule) Price(amount, mid money.Decimal, from, to string, places, scale int, mode money.RoundingMode) (Price, error) {
+	applied := s.Rate(mid, from, to, scale)
+	return s.PriceAt(amount, mid, applied, from, to, places, mode)
+}
+
+// PriceAt is like Price but uses an applied rate that was fixed earlier,
+// e.g. by a quote
+func (s *Schedule) PriceAt(amount, mid, applied money.Decimal, from, to string, places int, mode money.RoundingMode) (Price, error) {
+	if err := CheckAmount(amount); err != nil {
+		return Price{}, err
+	}
+	converted := amount.Mul(applied).Round(places, mode)
+	fee := s.Fee(from, to, converted, places, mode)
+	if !fee.IsZero() && fee.Cmp(converted) >= 0 {
+		return Price{}, &FeeExceedsAmountError{Fee: fee, Amount: converted}
+	}
+
+	return Price{
+		MidRate:         mid,
+		AppliedRate:     applied,
+		ConvertedAmount: converted,
+		Fee:             fee,
+		NetAmount:       converted.Sub(fee),
+	}, nil
+}
+
+// Validate checks that spreads and fees are well formed
+func (s *Schedule) Validate() error {
+	if s.DefaultSpread.Sign() < 0 || s.DefaultSpread.Cmp(money.NewFromInt(100)) >= 0 {
+		return fmt.Errorf("defaultSpread must be at least 0 and below 100 percent, got %s", s.DefaultSpread)
+	}
+	for pair, spread := range s.Spreads {
+		if err := checkPair(pair, false); err != nil {
+			return err
+		}
+		if spread.Bid.Sign() < 0 || spread.Bid.Cmp(money.NewFromInt(100)) >= 0 {
+			return fmt.Errorf("spread %s: bid must be at least 0 and below 100 percent, got %s", pair, spread.Bid)
+		}
+		if spread.Ask.Sign() < 0 {
+			return fmt.Errorf("spread %s: ask must not be negative, got %s", pair, spread.Ask)
+		}
+	}
+
+	for pair, fee := range s.Fees {
+		if err := checkPair(pair, true); err != nil {
+			return err
+		}
+		if err := fee.validate(); err != nil {
+			return fmt.Errorf("fee %s: %w", pair, err)
+		}
+	}
+	return nil
+}
+
+// validate checks a single fee rule
+func (f Fee) validate() error {
+	switch f.Type {
+	case Flat:
+		if f.Amount.Sign() < 0 {
+			return fmt.Errorf("amount must not be negative")
+		}
+	case Percentage:
+		if f.Percent.Sign() < 0 {
+			return fmt.Errorf("percent must not be negative")
+		}
+	case Tiered:
+		if len(f.Tiers) == 0 {
+			return fmt.Errorf("tiered fee has no tiers")
+		}
+		for i, tier := range f.Tiers {
+			if tier.Amount.Sign() < 0 || tier.Percent.Sign() < 0 {
+				return fmt.Errorf("tier %d: amount and percent must not be negative", i+1)
+			}
+			last := i == len(f.Tiers)-1
+			if tier.UpTo == nil && !last {
+				return fmt.Errorf("tier %d: only the last tier may omit upTo", i+1)
+			}
+			if i > 0 && tier.UpTo != nil && tier.UpTo.Cmp(*f.Tiers[i-1].UpTo) <= 0 {
+				return fmt.Errorf("tier %d: upTo must be greater than the previous tier", i+1)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown fee type %q, expected flat, percentage or tiered", f.Type)
+	}
+
+	if f.Min != nil && f.Max != nil && f.Min.Cmp(*f.Max) > 0 {
+		return fmt.Errorf("min %s is greater than max %s", f.Min, f.Max)
+	}
+	return nil
+}
+
+// checkPair verifies a "FROM/TO" key, optionally allowing wildcards
+func checkPair(pair string, wildcards bool) error {
+	from, to, ok := strings.Cut(pair, "/")
+	if !ok || from == "" || to == "" {
+		return fmt.Errorf("invalid currency pair %q, expected FROM/TO", pair)
+	}
+	if !wildcards && (from == Wildcard || to == Wildcard) {
+		return fmt.Errorf("invalid currency pair %q, wildcards are only allowed for fees", pair)
+	}
+	return nil
+}
+
+// LoadFile reads a pricing schedule from a JSON or YAML file
+func LoadFile(path string) (*Schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule Schedule
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &schedule)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &schedule)
+	default:
+		return nil, fmt.Errorf("unsupported pricing file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pricing file %s: %w", path, err)
+	}
+
+	if err := schedule.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid pricing file %s: %w", path, err)
+	}
+	return &schedule, nil
+}
@@ -0,0 +1,103 @@
+package rates
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"money"
+)
+
+// csvColumns is the required header of a rate CSV file
+var csvColumns = []string{"date", "base", "currency", "rate"}
+
+// ParseCSV reads snapshots from a CSV document with the header
+// "date,base,currency,rate" and one rate per row, e.g.
+//
+//	date,base,currency,rate
+//	2024-01-05,USD,EUR,0.9157
+//	2024-01-05,USD,UAH,37.98
+//
+// Rows with the same date form one snapshot and must share a base currency.
+func ParseCSV(r io.Reader) ([]Snapshot, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvColumns)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate CSV header: %w", err)
+	}
+	for i, column := range csvColumns {
+		if !strings.EqualFold(strings.TrimSpace(header[i]), column) {
+			return nil, fmt.Errorf("invalid rate CSV header %q, expected %q",
+				strings.Join(header, ","), strings.Join(csvColumns, ","))
+		}
+	}
+
+	type day struct {
+		base  string
+		rates map[string]money.Decimal
+	}
+	days := make(map[time.Time]*day)
+	var order []time.Time
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		date, err := time.Parse(time.DateOnly, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, record[0])
+		}
+		base, code := record[1], record[2]
+		if err := checkCode(base); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := checkCode(code); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rate, err := money.Parse(record[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		d, ok := days[date]
+		if !ok {
+			d = &day{base: base, rates: make(map[string]money.Decimal)}
+			days[date] = d
+			order = append(order, date)
+		}
+		if d.base != base {
+			return nil, fmt.Errorf("line %d: base %s differs from %s used earlier for %s",
+				line, base, d.base, record[0])
+		}
+		if _, dup := d.rates[code]; dup {
+			return nil, fmt.Errorf("line %d: duplicate rate for %s on %s", line, code, record[0])
+		}
+		d.rates[code] = rate
+	}
+
+	if len(order) == 0 {
+		return nil, fmt.Errorf("rate CSV contains no rates")
+	}
+
+	snapshots := make([]Snapshot, 0, len(order))
+	for _, date := range order {
+		table, err := NewTable(days[date].base, days[date].rates)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", date.Format(time.DateOnly), err)
+		}
+		snapshots = append(snapshots, Snapshot{Date: date, Table: table})
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,83 @@
+package rates
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"money"
+)
+
+// ECBBase is the base currency of ECB euro foreign exchange reference rates
+const ECBBase = "EUR"
+
+// ecbEnvelope mirrors the eurofxref XML published by the European Central
+// Bank (eurofxref-daily.xml, eurofxref-hist-90d.xml, eurofxref-hist.xml)
+type ecbEnvelope struct {
+	XMLName xml.Name `xml:"http://www.gesmes.org/xml/2002-08-01 Envelope"`
+	Cube    struct {
+		Days []struct {
+			Time  string `xml:"time,attr"`
+			Rates []struct {
+				Currency string `xml:"currency,attr"`
+				Rate     string `xml:"rate,attr"`
+			} `xml:"http://www.ecb.int/vocabulary/2002-08-01/eurofxref Cube"`
+		} `xml:"http://www.ecb.int/vocabulary/2002-08-01/eurofxref Cube"`
+	} `xml:"http://www.ecb.int/vocabulary/2002-08-01/eurofxref Cube"`
+}
+
+// ParseECB reads snapshots from an ECB eurofxref XML document.
+// Each dated Cube becomes one snapshot with EUR as the base currency.
+func ParseECB(r io.Reader) ([]Snapshot, error) {
+	var doc ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid eurofxref document: %w", err)
+	}
+	if len(doc.Cube.Days) == 0 {
+		return nil, fmt.Errorf("eurofxref document contains no rates")
+	}
+
+	snapshots := make([]Snapshot, 0, len(doc.Cube.Days))
+	for _, day := range doc.Cube.Days {
+		date, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Cube time %q", day.Time)
+		}
+
+		rates := make(map[string]money.Decimal, len(day.Rates))
+		for _, entry := range day.Rates {
+			if err := checkCode(entry.Currency); err != nil {
+				return nil, fmt.Errorf("%s: %w", day.Time, err)
+			}
+			if _, dup := rates[entry.Currency]; dup {
+				return nil, fmt.Errorf("%s: duplicate rate for %s", day.Time, entry.Currency)
+			}
+			rate, err := money.Parse(entry.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("%s: rate for %s: %w", day.Time, entry.Currency, err)
+			}
+			rates[entry.Currency] = rate
+		}
+
+		table, err := NewTable(ECBBase, rates)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", day.Time, err)
+		}
+		snapshots = append(snapshots, Snapshot{Date: date, Table: table})
+	}
+	return snapshots, nil
+}
+
+// checkCode verifies that code looks like an ISO 4217 alphabetic code
+func checkCode(code string) error {
+	if len(code) != 3 {
+		return fmt.Errorf("invalid currency code %q", code)
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return fmt.Errorf("invalid currency code %q", code)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+// Package rates stores exchange rates against a base currency and derives
+// cross rates between any two known currencies.
+package rates
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"money"
+)
+
+// Scale is the number of fraction digits derived rates are rounded to
+const Scale = 10
+
+// Rate is an exchange rate together with the currencies it was derived through
+type Rate struct {
+	Value money.Decimal
+	// Path starts with the source and ends with the target currency
+	Path []string
+	// Date is the effective date of the snapshot the rate came from
+	Date time.Time
+}
+
+// NotFoundError is returned when a currency pair cannot be priced
+type NotFoundError struct {
+	From string
+	To   string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("conversion rate not found for %s to %s", e.From, e.To)
+}
+
+// NoSnapshotError is returned when no snapshot covers a value date
+type NoSnapshotError struct {
+	Date time.Time
+}
+
+func (e *NoSnapshotError) Error() string {
+	return fmt.Sprintf("no exchange rates are available for %s", e.Date.Format(time.DateOnly))
+}
+
+// Provider looks up exchange rates. Implementations backed by slow sources
+// must give up and return the context's error once it is done.
+type Provider interface {
+	RateContext(ctx context.Context, from, to string) (Rate, error)
+	RateOnContext(ctx context.Context, date time.Time, from, to string) (Rate, error)
+}
+
+// Day truncates t to midnight UTC of its calendar day
+func Day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Table is an immutable set of rates against a single base currency
+type Table struct {
+	base  string
+	rates map[string]money.Decimal
+}
+
+// NewTable creates a table where rates[c] is how many units of c
+// one unit of base buys
+func NewTable(base string, rates map[string]money.Decimal) (*Table, error) {
+	if base == "" {
+		return nil, fmt.Errorf("base currency is required")
+	}
+
+	copied := make(map[string]money.Decimal, len(rates))
+	for code, rate := range rates {
+		if rate.Sign() <= 0 {
+			return nil, fmt.Errorf("rate for %s must be positive, got %s", code, rate)
+		}
+		copied[code] = rate
+	}
+	return &Table{base: base, rates: copied}, nil
+}
+
+// Base returns the base currency of the table
+func (t *Table) Base() string {
+	return t.base
+}
+
+// Rates returns a copy of the rates against the base currency
+func (t *Table) Rates() map[string]money.Decimal {
+	copied := make(map[string]money.Decimal, len(t.rates))
+	for code, rate := range t.rates {
+		copied[code] = rate
+	}
+	return copied
+}
+
+// Rate returns the rate from one currency to another, triangulating
+// through the base currency when neither side is the base
+func (t *Table) Rate(from, to string) (Rate, error) {
+	fromRate, okFrom := t.baseRate(from)
+	toRate, okTo := t.baseRate(to)
+	if !okFrom || !okTo {
+		return Rate{}, &NotFoundError{From: from, To: to}
+	}
+
+	var path []string
+	switch {
+	case from == to:
+		path = []string{from}
+	case from == t.base || to == t.base:
+		path = []string{from, to}
+	default:
+		path = []string{from, t.base, to}
+	}
+
+	value, err := toRate.Div(fromRate)
+	if err != nil {
+		return Rate{}, err
+	}
+	value = value.Round(Scale, money.HalfEven).Normalize()
+
+	return Rate{Value: value, Path: path}, nil
+}
+
+// baseRate returns how many units of code one unit of the base buys
+func (t *Table) baseRate(code string) (money.Decimal, bool) {
+	if code == t.base {
+		return money.NewFromInt(1), true
+	}
+	rate, ok := t.rates[code]
+	return rate, ok
+}
+
+// Snapshot is a rate table effective from a date until the next snapshot
+type Snapshot struct {
+	Date  time.Time
+	Table *Table
+}
+
+// Engine keeps dated rate snapshots; the latest one holds the current rates.
+// It is safe for concurrent use.
+type Engine struct {
+	mu sync.RWMutex
+	// snapshots is sorted by date
+	snapshots []Snapshot
+}
+
+// NewEngine creates an engine whose current rates, effective from today,
+// are given against base
+func NewEngine(base string, rates map[string]money.Decimal) (*Engine, error) {
+	e := &Engine{}
+	if err := e.Replace(base, rates); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Base returns the base currency of the current rates
+func (e *Engine) Base() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.current().Table.base
+}
+
+// Current returns the snapshot holding the current rates
+func (e *Engine) Current() Snapshot {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.current()
+}
+
+// Set adds or replaces the rate of a single currency against the base,
+// effective from today
+func (e *Engine) Set(code string, rate money.Decimal) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	current := e.current().Table
+	if code == current.base {
+		return fmt.Errorf("cannot set the rate of base currency %s", code)
+	}
+	rates := current.Rates()
+	rates[code] = rate
+	table, err := NewTable(current.base, rates)
+	if err != nil {
+		return err
+	}
+	e.put(Snapshot{Date: Day(time.Now()), Table: table})
+	return nil
+}
+
+// Replace swaps the base currency and all rates at once, effective from today
+func (e *Engine) Replace(base string, rates map[string]money.Decimal) error {
+	table, err := NewTable(base, rates)
+	if err != nil {
+		return err
+	}
+	return e.AddSnapshot(Snapshot{Date: time.Now(), Table: table})
+}
+
+// AddSnapshot stores a table effective from the snapshot's date,
+// replacing any snapshot for the same day
+func (e *Engine) AddSnapshot(snapshot Snapshot) error {
+	if snapshot.Table == nil {
+		return fmt.Errorf("rate snapshot has no table")
+	}
+	snapshot.Date = Day(snapshot.Date)
+	if snapshot.Date.After(Day(time.Now())) {
+		return fmt.Errorf("rate snapshot for %s is in the future", snapshot.Date.Format(time.DateOnly))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.put(snapshot)
+	return nil
+}
+
+// Swap atomically replaces every stored snapshot. The snapshots are
+// validated first, so on error the engine keeps its previous rates.
+func (e *Engine) Swap(snapshots []Snapshot) error {
+	if len(snapshots) == 0 {
+		return fmt.Errorf("no rate snapshots to load")
+	}
+
+	today := Day(time.Now())
+	sorted := make([]Snapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if snapshot.Table == nil {
+			return fmt.Errorf("rate snapshot has no table")
+		}
+		snapshot.Date = Day(snapshot.Date)
+		if snapshot.Date.After(today) {
+			return fmt.Errorf("rate snapshot for %s is in the future", snapshot.Date.Format(time.DateOnly))
+		}
+		sorted = append(sorted, snapshot)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Date.Equal(sorted[i-1].Date) {
+			return fmt.Errorf("duplicate rate snapshot for %s", sorted[i].Date.Format(time.DateOnly))
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.snapshots = sorted
+	return nil
+}
+
+// Rate returns the current rate from one currency to another
+func (e *Engine) Rate(from, to string) (Rate, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.current().rate(from, to)
+}
+
+// RateOn returns the rate that applied on the given date
+func (e *Engine) RateOn(date time.Time, from, to string) (Rate, error) {
+	date = Day(date)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	// Find the last snapshot effective on or before the date
+	i := sort.Search(len(e.snapshots), func(i int) bool {
+		return e.snapshots[i].Date.After(date)
+	})
+	if i == 0 {
+		return Rate{}, &NoSnapshotError{Date: date}
+	}
+	return e.snapshots[i-1].rate(from, to)
+}
+
+// RateContext implements Provider. The engine answers from memory, so the
+// context is only checked before the lookup.
+func (e *Engine) RateContext(ctx context.Context, from, to string) (Rate, error) {
+	if err := ctx.Err(); err != nil {
+		return Rate{}, err
+	}
+	return e.Rate(from, to)
+}
+
+// RateOnContext implements Provider
+func (e *Engine) RateOnContext(ctx context.Context, date time.Time, from, to string) (Rate, error) {
+	if err := ctx.Err(); err != nil {
+		return Rate{}, err
+	}
+	return e.RateOn(date, from, to)
+}
+
+// rate prices a pair from the snapshot and records its date
+func (s Snapshot) rate(from, to string) (Rate, error) {
+	rate, err := s.Table.Rate(from, to)
+	if err != nil {
+		return Rate{}, err
+	}
+	rate.Date = s.Date
+	return rate, nil
+}
+
+// current returns the latest snapshot; callers must hold the lock
+func (e *Engine) current() Snapshot {
+	return e.snapshots[len(e.snapshots)-1]
+}
+
+// put inserts a snapshot keeping the list sorted; callers must hold the lock
+func (e *Engine) put(snapshot Snapshot) {
+	i := sort.Search(len(e.snapshots), func(i int) bool {
+		return !e.snapshots[i].Date.Before(snapshot.Date)
+	})
+	if i < len(e.snapshots) && e.snapshots[i].Date.Equal(snapshot.Date) {
+		e.snapshots[i] = snapshot
+		return
+	}
+	e.snapshots = append(e.snapshots, Snapshot{})
+	copy(e.snapshots[i+1:], e.snapshots[i:])
+	e.snapshots[i] = snapshot
+}
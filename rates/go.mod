@@ -0,0 +1,7 @@
+module rates
+
+go 1.21
+
+require money v0.0.0
+
+replace money => ../money
@@ -0,0 +1,203 @@
+package rates
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LoadFile reads snapshots from a single rate file.
+// Files ending in .xml are read as ECB eurofxref XML, .csv files as rate CSV.
+func LoadFile(path string) ([]Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var snapshots []Snapshot
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		snapshots, err = ParseECB(f)
+	case ".csv":
+		snapshots, err = ParseCSV(f)
+	default:
+		return nil, fmt.Errorf("%s: unsupported rate file type", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return snapshots, nil
+}
+
+// Load reads snapshots from a rate file, or from every .xml and .csv file
+// in a directory. Snapshots for the same date from different files are
+// merged; they must agree on the base currency and on any shared rates.
+func Load(path string) ([]Snapshot, error) {
+	files, err := rateFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: no rate files found", path)
+	}
+
+	merged := make(map[time.Time]Snapshot)
+	for _, file := range files {
+		snapshots, err := LoadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, snapshot := range snapshots {
+			date := Day(snapshot.Date)
+			if existing, ok := merged[date]; ok {
+				if snapshot, err = mergeSnapshots(existing, snapshot); err != nil {
+					return nil, fmt.Errorf("%s: %w", file, err)
+				}
+			}
+			merged[date] = Snapshot{Date: date, Table: snapshot.Table}
+		}
+	}
+
+	snapshots := make([]Snapshot, 0, len(merged))
+	for _, snapshot := range merged {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Date.Before(snapshots[j].Date) })
+	return snapshots, nil
+}
+
+// rateFiles lists the rate files at path, sorted by name
+func rateFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.Type().IsRegular() && (ext == ".xml" || ext == ".csv") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// mergeSnapshots combines two snapshots for the same date
+func mergeSnapshots(a, b Snapshot) (Snapshot, error) {
+	date := a.Date.Format(time.DateOnly)
+	if a.Table.base != b.Table.base {
+		return Snapshot{}, fmt.Errorf("%s: base %s conflicts with %s", date, b.Table.base, a.Table.base)
+	}
+
+	rates := a.Table.Rates()
+	for code, rate := range b.Table.rates {
+		if existing, ok := rates[code]; ok && existing.Cmp(rate) != 0 {
+			return Snapshot{}, fmt.Errorf("%s: rate %s for %s conflicts with %s", date, rate, code, existing)
+		}
+		rates[code] = rate
+	}
+
+	table, err := NewTable(a.Table.base, rates)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{Date: a.Date, Table: table}, nil
+}
+
+// Watcher keeps an engine in sync with a rate file or directory.
+// It polls the modification times of the rate files and reloads all of
+// them when anything changes; invalid files leave the current rates in place.
+type Watcher struct {
+	path     string
+	engine   *Engine
+	interval time.Duration
+	// state is the last seen name, size and modification time of each file
+	state string
+}
+
+// NewWatcher creates a watcher that loads rates from path into engine,
+// checking for changes every interval
+func NewWatcher(path string, engine *Engine, interval time.Duration) *Watcher {
+	return &Watcher{path: path, engine: engine, interval: interval}
+}
+
+// Reload loads the rate files and swaps them into the engine
+func (w *Watcher) Reload() error {
+	state, err := w.fileState()
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := Load(w.path)
+	if err != nil {
+		return err
+	}
+	if err := w.engine.Swap(snapshots); err != nil {
+		return fmt.Errorf("%s: %w", w.path, err)
+	}
+	w.state = state
+	return nil
+}
+
+// Run polls for changes until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		state, err := w.fileState()
+		if err != nil {
+			log.Printf("rates: %v", err)
+			continue
+		}
+		if state == w.state {
+			continue
+		}
+
+		if err := w.Reload(); err != nil {
+			log.Printf("rates: reload failed, keeping current rates: %v", err)
+			// Do not retry until the files change again
+			w.state = state
+			continue
+		}
+		log.Printf("rates: reloaded from %s", w.path)
+	}
+}
+
+// fileState summarises the rate files so changes can be detected
+func (w *Watcher) fileState() (string, error) {
+	files, err := rateFiles(w.path)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String(), nil
+}
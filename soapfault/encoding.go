@@ -0,0 +1,114 @@
+package soapfault
+
+import "encoding/xml"
+
+// Envelope namespaces of the SOAP versions
+const (
+	SOAP11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	SOAP12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// Detail is the error element in the detail of a fault
+type Detail struct {
+	XMLName xml.Name  `xml:"http://practice/soap/fault error"`
+	Code    ErrorCode `xml:"code"`
+	Message string    `xml:"message,omitempty"`
+	Element string    `xml:"element,omitempty"`
+}
+
+// Unqualified is the text of an element that is in no namespace, such as
+// the SOAP 1.1 faultcode. It resets the default namespace of its parent.
+type Unqualified struct {
+	NoNamespace string `xml:"xmlns,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// SOAP11Fault is the SOAP 1.1 Fault element
+type SOAP11Fault struct {
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Fault"`
+	// SoapPrefix binds the soap prefix used by the qualified fault code
+	SoapPrefix string `xml:"xmlns:soap,attr"`
+	// SubcodePrefix binds the prefix of an extension fault code
+	SubcodePrefix *xml.Attr     `xml:",attr,omitempty"`
+	FaultCode     Unqualified   `xml:"faultcode"`
+	FaultString   Unqualified   `xml:"faultstring"`
+	Detail        *SOAP11Detail `xml:"detail,omitempty"`
+}
+
+// SOAP11Detail is the unqualified detail element of a SOAP 1.1 fault
+type SOAP11Detail struct {
+	NoNamespace string `xml:"xmlns,attr"`
+	Error       *Detail
+}
+
+// SOAP11 returns the fault as a SOAP 1.1 Fault element, e.g. with the fault
+// code soap:Client, or the subcode, e.g. wsse:FailedAuthentication, if set
+func (e *Error) SOAP11() *SOAP11Fault {
+	fault := &SOAP11Fault{
+		SoapPrefix:  SOAP11Namespace,
+		FaultCode:   Unqualified{Value: "soap:" + string(e.Code)},
+		FaultString: Unqualified{Value: e.String},
+		Detail:      &SOAP11Detail{Error: e.Detail()},
+	}
+	if e.Subcode != nil {
+		fault.SubcodePrefix = e.Subcode.prefixAttr()
+		fault.FaultCode.Value = e.Subcode.Prefix + ":" + e.Subcode.Local
+	}
+	return fault
+}
+
+// SOAP12Fault is the SOAP 1.2 Fault element
+type SOAP12Fault struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2003/05/soap-envelope Fault"`
+	// EnvPrefix binds the env prefix used by the qualified fault code
+	EnvPrefix string `xml:"xmlns:env,attr"`
+	// SubcodePrefix binds the prefix of an extension subcode
+	SubcodePrefix *xml.Attr         `xml:",attr,omitempty"`
+	Code          SOAP12FaultCode   `xml:"http://www.w3.org/2003/05/soap-envelope Code"`
+	Reason        SOAP12FaultReason `xml:"http://www.w3.org/2003/05/soap-envelope Reason"`
+	Detail        *SOAP12Detail     `xml:"http://www.w3.org/2003/05/soap-envelope Detail,omitempty"`
+}
+
+// SOAP12FaultCode holds the qualified fault code, e.g. env:Sender, and
+// optionally a subcode refining it
+type SOAP12FaultCode struct {
+	Value   string           `xml:"http://www.w3.org/2003/05/soap-envelope Value"`
+	Subcode *SOAP12FaultCode `xml:"http://www.w3.org/2003/05/soap-envelope Subcode,omitempty"`
+}
+
+// SOAP12FaultReason holds the human readable fault description
+type SOAP12FaultReason struct {
+	Text SOAP12FaultText `xml:"http://www.w3.org/2003/05/soap-envelope Text"`
+}
+
+// SOAP12FaultText is a fault description in a single language
+type SOAP12FaultText struct {
+	Lang  string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Value string `xml:",chardata"`
+}
+
+// SOAP12Detail carries the error element of a SOAP 1.2 fault
+type SOAP12Detail struct {
+	Error *Detail
+}
+
+// SOAP12 returns the fault as a SOAP 1.2 Fault element, e.g. with the fault
+// code env:Sender and the subcode, if set, e.g. wsse:FailedAuthentication
+func (e *Error) SOAP12() *SOAP12Fault {
+	fault := &SOAP12Fault{
+		EnvPrefix: SOAP12Namespace,
+		Code:      SOAP12FaultCode{Value: "env:" + e.Code.SOAP12()},
+		Reason:    SOAP12FaultReason{Text: SOAP12FaultText{Lang: "en", Value: e.String}},
+		Detail:    &SOAP12Detail{Error: e.Detail()},
+	}
+	if e.Subcode != nil {
+		fault.SubcodePrefix = e.Subcode.prefixAttr()
+		fault.Code.Subcode = &SOAP12FaultCode{Value: e.Subcode.Prefix + ":" + e.Subcode.Local}
+	}
+	return fault
+}
+
+// prefixAttr is the xmlns attribute binding the prefix of the subcode
+func (s *Subcode) prefixAttr() *xml.Attr {
+	return &xml.Attr{Name: xml.Name{Local: "xmlns:" + s.Prefix}, Value: s.Namespace}
+}
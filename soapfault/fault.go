@@ -0,0 +1,172 @@
+// Package soapfault is the SOAP fault type shared by the currency services.
+// An Error carries the fault code, which says whether the request or the
+// service is to blame, and a machine-readable error code. It is written as a
+// SOAP 1.1 or SOAP 1.2 fault whose detail holds an error element:
+//
+//	<detail>
+//	  <error xmlns="http://practice/soap/fault">
+//	    <code>UNKNOWN_CURRENCY</code>
+//	    <message>fromCurrency: unknown currency code "XXX"</message>
+//	  </error>
+//	</detail>
+package soapfault
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// DetailNamespace is the namespace of the error element in fault details
+const DetailNamespace = "http://practice/soap/fault"
+
+// Code is a SOAP fault code in SOAP 1.1 terms
+type Code string
+
+// Fault codes
+const (
+	// VersionMismatch is for envelopes in an unsupported namespace
+	VersionMismatch Code = "VersionMismatch"
+	// MustUnderstand is for mandatory headers the service does not process
+	MustUnderstand Code = "MustUnderstand"
+	// Client is for requests that cannot succeed unless they are changed
+	Client Code = "Client"
+	// Server is for failures of the service; the request may succeed later
+	Server Code = "Server"
+)
+
+// SOAP12 returns the SOAP 1.2 name of the code: Client and Server are
+// called Sender and Receiver
+func (c Code) SOAP12() string {
+	switch c {
+	case Client:
+		return "Sender"
+	case Server:
+		return "Receiver"
+	}
+	return string(c)
+}
+
+// ErrorCode identifies the cause of a fault for programs
+type ErrorCode string
+
+// Error codes
+const (
+	UnsupportedMediaType ErrorCode = "UNSUPPORTED_MEDIA_TYPE"
+	UnsupportedEnvelope  ErrorCode = "UNSUPPORTED_ENVELOPE"
+	MalformedRequest     ErrorCode = "MALFORMED_REQUEST"
+	RequestTooLarge      ErrorCode = "REQUEST_TOO_LARGE"
+	XMLLimitExceeded     ErrorCode = "XML_LIMIT_EXCEEDED"
+	DTDNotAllowed        ErrorCode = "DTD_NOT_ALLOWED"
+	UnknownOperation     ErrorCode = "UNKNOWN_OPERATION"
+	HeaderNotUnderstood  ErrorCode = "HEADER_NOT_UNDERSTOOD"
+	InvalidHeader        ErrorCode = "INVALID_HEADER"
+	InvalidAddressing    ErrorCode = "INVALID_ADDRESSING_HEADER"
+	AddressingRequired   ErrorCode = "ADDRESSING_HEADER_REQUIRED"
+	ActionMismatch       ErrorCode = "ACTION_MISMATCH"
+	ActionNotSupported   ErrorCode = "ACTION_NOT_SUPPORTED"
+	InvalidRequest       ErrorCode = "INVALID_REQUEST"
+	UnknownCurrency      ErrorCode = "UNKNOWN_CURRENCY"
+	InactiveCurrency     ErrorCode = "INACTIVE_CURRENCY"
+	RateNotFound         ErrorCode = "RATE_NOT_FOUND"
+	NoRatesForDate       ErrorCode = "NO_RATES_FOR_DATE"
+	InvalidValueDate     ErrorCode = "INVALID_VALUE_DATE"
+	InvalidAmount        ErrorCode = "INVALID_AMOUNT"
+	AmountTooSmall       ErrorCode = "AMOUNT_TOO_SMALL"
+	InvalidBatch         ErrorCode = "INVALID_BATCH"
+	QuoteNotFound        ErrorCode = "QUOTE_NOT_FOUND"
+	QuoteExpired         ErrorCode = "QUOTE_EXPIRED"
+	InvalidCallback      ErrorCode = "INVALID_CALLBACK"
+	QueueFull            ErrorCode = "QUEUE_FULL"
+	FailedAuthentication ErrorCode = "FAILED_AUTHENTICATION"
+	InvalidSecurity      ErrorCode = "INVALID_SECURITY"
+	Timeout              ErrorCode = "TIMEOUT"
+	InternalError        ErrorCode = "INTERNAL_ERROR"
+)
+
+// Subcode is a fault code defined by a SOAP extension, e.g.
+// wsse:FailedAuthentication. It replaces the fault code in SOAP 1.1 and
+// refines it as a Subcode in SOAP 1.2.
+type Subcode struct {
+	Namespace string
+	// Prefix is bound to Namespace in the fault
+	Prefix string
+	Local  string
+}
+
+// Error is an error that is reported to the caller as a SOAP fault
+type Error struct {
+	Code      Code
+	ErrorCode ErrorCode
+	// Subcode, if set, is the fault code of the SOAP extension that failed
+	Subcode *Subcode
+	// String is the human-readable faultstring
+	String string
+	// Element is the path of the offending request element, if known
+	Element string
+	// Err is the underlying error. Its message is the detail message of
+	// faults caused by the request; that of Server faults is only logged.
+	Err error
+}
+
+// New creates a fault error
+func New(code Code, errorCode ErrorCode, faultString string, err error) *Error {
+	return &Error{Code: code, ErrorCode: errorCode, String: faultString, Err: err}
+}
+
+// From returns the fault error in err's chain. Errors without one are
+// failures of the service and become Server faults, with the Timeout code
+// when the operation ran out of time.
+func From(err error) *Error {
+	var fault *Error
+	if errors.As(err, &fault) {
+		return fault
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return New(Server, Timeout, "Operation timed out", err)
+	}
+	return New(Server, InternalError, "Failed to process request", err)
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.String
+	}
+	return e.String + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus is the status code of responses carrying the fault: 500 for
+// Server faults, 415 for unsupported media types, 413 for request bodies
+// over the size limit and 400 for other faults caused by the request
+func (e *Error) HTTPStatus() int {
+	switch {
+	case e.Code == Server:
+		return http.StatusInternalServerError
+	case e.ErrorCode == UnsupportedMediaType:
+		return http.StatusUnsupportedMediaType
+	case e.ErrorCode == RequestTooLarge:
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// Detail returns the structured detail of the fault. Server faults carry
+// only their error code, as their errors are internal to the service; they
+// are logged instead.
+func (e *Error) Detail() *Detail {
+	detail := &Detail{Code: e.ErrorCode, Element: e.Element}
+	switch {
+	case e.Err == nil:
+	case e.Code == Server:
+		log.Printf("soapfault: %s: %v", e.ErrorCode, e.Err)
+	default:
+		detail.Message = e.Err.Error()
+	}
+	return detail
+}
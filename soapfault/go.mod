@@ -0,0 +1,3 @@
+module soapfault
+
+go 1.21
@@ -0,0 +1,123 @@
+// Package soapheader processes the entries of the SOAP Header. A Registry
+// maps the qualified names of the header entries a service understands to
+// handlers, which may add values to the request context and entries to the
+// SOAP Header of the response. Entries marked mustUnderstand that no handler
+// understands fail the request with a MustUnderstand fault, as the SOAP 1.1
+// and 1.2 specifications require.
+package soapheader
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Entry is a header entry of a request, kept as its tokens so it can be
+// decoded by the handler of its name
+type Entry struct {
+	Name xml.Name
+	Attr []xml.Attr
+	// tokens are the tokens of the element, from its start to its end
+	tokens []xml.Token
+}
+
+// ReadEntries reads the entries of a SOAP Header whose start element has
+// just been read, up to and including its end element
+func ReadEntries(decoder xml.TokenReader) ([]*Entry, error) {
+	var entries []*Entry
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			entry, err := readEntry(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case xml.EndElement:
+			return entries, nil
+		}
+	}
+}
+
+// readEntry records the tokens of an entry whose start element has been read
+func readEntry(decoder xml.TokenReader, start xml.StartElement) (*Entry, error) {
+	start = WithoutNamespaceDeclarations(start)
+	entry := &Entry{Name: start.Name, Attr: start.Attr, tokens: []xml.Token{start}}
+	for depth := 1; depth > 0; {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			token = WithoutNamespaceDeclarations(t)
+			depth++
+		case xml.EndElement:
+			depth--
+		default:
+			token = xml.CopyToken(token)
+		}
+		entry.tokens = append(entry.tokens, token)
+	}
+	return entry, nil
+}
+
+// WithoutNamespaceDeclarations copies a start element without its xmlns
+// attributes. A decoder has already resolved the names of the element and
+// its attributes, so the declarations would only be applied twice when
+// recorded tokens are decoded again, as header entries are.
+func WithoutNamespaceDeclarations(start xml.StartElement) xml.StartElement {
+	attrs := make([]xml.Attr, 0, len(start.Attr))
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	return xml.StartElement{Name: start.Name, Attr: attrs}
+}
+
+// Decode unmarshals the entry into v, as xml.Unmarshal does
+func (e *Entry) Decode(v interface{}) error {
+	return xml.NewTokenDecoder(&tokenReader{tokens: e.tokens}).Decode(v)
+}
+
+// Value returns the value of an attribute of the entry
+func (e *Entry) Value(name xml.Name) (string, bool) {
+	for _, attr := range e.Attr {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// String returns the qualified name of the entry for messages
+func (e *Entry) String() string {
+	return fmt.Sprintf("{%s}%s", e.Name.Space, e.Name.Local)
+}
+
+// tokenReader replays recorded tokens
+type tokenReader struct {
+	tokens []xml.Token
+}
+
+func (r *tokenReader) Token() (xml.Token, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	token := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return token, nil
+}
@@ -0,0 +1,170 @@
+package soapheader
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"soapfault"
+)
+
+// Actors and roles of entries that are processed by the service
+const (
+	soap11ActorNext    = "http://schemas.xmlsoap.org/soap/actor/next"
+	soap12RoleNext     = "http://www.w3.org/2003/05/soap-envelope/role/next"
+	soap12RoleUltimate = "http://www.w3.org/2003/05/soap-envelope/role/ultimateReceiver"
+)
+
+// Handler processes a header entry. It returns the context of the request,
+// to which it may add values, or an error, which fails the request; errors
+// without a *soapfault.Error in their chain become INVALID_HEADER faults.
+type Handler func(ctx context.Context, entry *Entry) (context.Context, error)
+
+// Registry holds the handlers of the header entries a service understands
+type Registry struct {
+	handlers map[xml.Name]Handler
+}
+
+// NewRegistry creates a registry that understands the CorrelationID and
+// Locale headers
+func NewRegistry() *Registry {
+	r := &Registry{handlers: make(map[xml.Name]Handler)}
+	r.Handle(CorrelationIDName, handleCorrelationID)
+	r.Handle(LocaleName, handleLocale)
+	return r
+}
+
+// Handle registers the handler of the entries with the given name,
+// replacing any handler registered before
+func (r *Registry) Handle(name xml.Name, handler Handler) {
+	r.handlers[name] = handler
+}
+
+// Process checks that every mandatory entry targeted at the service is
+// understood, then passes the entries to their handlers in document order.
+// namespace is the envelope namespace, which selects the SOAP version of
+// the mustUnderstand and actor or role attributes.
+//
+// The returned context collects the entries of the response header, see
+// ResponseHeaders, even if Process fails: for SOAP 1.2 a MustUnderstand
+// fault is accompanied by a NotUnderstood entry for each entry.
+func (r *Registry) Process(ctx context.Context, namespace string, entries []*Entry) (context.Context, error) {
+	ctx = context.WithValue(ctx, responseKey{}, &response{})
+
+	var notUnderstood []string
+	for _, entry := range entries {
+		if _, ok := r.handlers[entry.Name]; ok || !targeted(entry, namespace) || !mustUnderstand(entry, namespace) {
+			continue
+		}
+		notUnderstood = append(notUnderstood, entry.String())
+		if namespace == soapfault.SOAP12Namespace {
+			AddResponseHeader(ctx, &NotUnderstood{Name: entry.Name})
+		}
+	}
+	if len(notUnderstood) > 0 {
+		return ctx, soapfault.New(soapfault.MustUnderstand, soapfault.HeaderNotUnderstood, "Header not understood",
+			fmt.Errorf("mandatory header %s is not understood", strings.Join(notUnderstood, ", ")))
+	}
+
+	seen := make(map[xml.Name]bool)
+	for _, entry := range entries {
+		handler, ok := r.handlers[entry.Name]
+		if !ok || !targeted(entry, namespace) {
+			continue
+		}
+		if seen[entry.Name] {
+			return ctx, soapfault.New(soapfault.Client, soapfault.InvalidHeader, "Invalid header",
+				fmt.Errorf("multiple %s headers", entry.Name.Local))
+		}
+		seen[entry.Name] = true
+
+		var err error
+		if ctx, err = handler(ctx, entry); err != nil {
+			var fault *soapfault.Error
+			if errors.As(err, &fault) {
+				return ctx, fault
+			}
+			return ctx, soapfault.New(soapfault.Client, soapfault.InvalidHeader, "Invalid header",
+				fmt.Errorf("%s: %w", entry.Name.Local, err))
+		}
+	}
+	return ctx, nil
+}
+
+// mustUnderstand reports whether an entry is mandatory
+func mustUnderstand(entry *Entry, namespace string) bool {
+	value, _ := entry.Value(xml.Name{Space: namespace, Local: "mustUnderstand"})
+	value = strings.TrimSpace(value)
+	return value == "1" || value == "true"
+}
+
+// targeted reports whether an entry is meant for the service, the ultimate
+// receiver of the message, rather than for an intermediary
+func targeted(entry *Entry, namespace string) bool {
+	if namespace == soapfault.SOAP12Namespace {
+		role, _ := entry.Value(xml.Name{Space: namespace, Local: "role"})
+		return role == "" || role == soap12RoleNext || role == soap12RoleUltimate
+	}
+	actor, _ := entry.Value(xml.Name{Space: namespace, Local: "actor"})
+	return actor == "" || actor == soap11ActorNext
+}
+
+// NotUnderstood is the SOAP 1.2 header entry naming a mandatory entry that
+// was not understood
+type NotUnderstood struct {
+	Name xml.Name
+}
+
+// MarshalXML implements xml.Marshaler. The qname attribute refers to the
+// entry with the prefix h, which is bound to the namespace of the entry.
+func (n *NotUnderstood) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{
+		Name: xml.Name{Space: soapfault.SOAP12Namespace, Local: "NotUnderstood"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "qname"}, Value: n.Name.Local}},
+	}
+	if n.Name.Space != "" {
+		start.Attr = []xml.Attr{
+			{Name: xml.Name{Local: "xmlns:h"}, Value: n.Name.Space},
+			{Name: xml.Name{Local: "qname"}, Value: "h:" + n.Name.Local},
+		}
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+type responseKey struct{}
+
+// response collects the entries of the response header
+type response struct {
+	mu      sync.Mutex
+	entries []interface{}
+}
+
+// AddResponseHeader adds an entry, a value encoding/xml can marshal, to the
+// header of the response to the request of ctx. It does nothing for
+// contexts that did not come from Process.
+func AddResponseHeader(ctx context.Context, entry interface{}) {
+	r, ok := ctx.Value(responseKey{}).(*response)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// ResponseHeaders returns the entries of the response header
+func ResponseHeaders(ctx context.Context) []interface{} {
+	r, ok := ctx.Value(responseKey{}).(*response)
+	if !ok {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]interface{}(nil), r.entries...)
+}
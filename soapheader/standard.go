@@ -0,0 +1,89 @@
+package soapheader
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Namespace is the namespace of the header entries defined by the services
+const Namespace = "http://practice/soap/header"
+
+// Names of the header entries every registry understands
+var (
+	CorrelationIDName = xml.Name{Space: Namespace, Local: "CorrelationID"}
+	LocaleName        = xml.Name{Space: Namespace, Local: "Locale"}
+)
+
+// CorrelationID identifies a request across the systems that handle it.
+// The service echoes it in the header of the response.
+type CorrelationID struct {
+	XMLName xml.Name `xml:"http://practice/soap/header CorrelationID"`
+	Value   string   `xml:",chardata"`
+}
+
+// Locale is the language the client prefers, as a BCP 47 language tag,
+// e.g. en-US. The service echoes it in the header of the response.
+type Locale struct {
+	XMLName xml.Name `xml:"http://practice/soap/header Locale"`
+	Value   string   `xml:",chardata"`
+}
+
+// Length limits of header values
+const (
+	maxCorrelationID = 128
+	// maxLocale is the longest language tag implementations must support
+	maxLocale = 35
+)
+
+var (
+	// correlationIDPattern allows the characters of UUIDs, trace IDs and the like
+	correlationIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:/-]+$`)
+	localePattern        = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+)
+
+type correlationIDKey struct{}
+
+type localeKey struct{}
+
+// handleCorrelationID keeps the correlation ID in the context and echoes it
+func handleCorrelationID(ctx context.Context, entry *Entry) (context.Context, error) {
+	var header CorrelationID
+	if err := entry.Decode(&header); err != nil {
+		return ctx, err
+	}
+	value := strings.TrimSpace(header.Value)
+	if len(value) > maxCorrelationID || !correlationIDPattern.MatchString(value) {
+		return ctx, fmt.Errorf("invalid correlation ID %q", value)
+	}
+	AddResponseHeader(ctx, &CorrelationID{Value: value})
+	return context.WithValue(ctx, correlationIDKey{}, value), nil
+}
+
+// handleLocale keeps the locale in the context and echoes it
+func handleLocale(ctx context.Context, entry *Entry) (context.Context, error) {
+	var header Locale
+	if err := entry.Decode(&header); err != nil {
+		return ctx, err
+	}
+	value := strings.TrimSpace(header.Value)
+	if len(value) > maxLocale || !localePattern.MatchString(value) {
+		return ctx, fmt.Errorf("invalid locale %q", value)
+	}
+	AddResponseHeader(ctx, &Locale{Value: value})
+	return context.WithValue(ctx, localeKey{}, value), nil
+}
+
+// CorrelationIDFromContext returns the correlation ID of a request, if any
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	value, ok := ctx.Value(correlationIDKey{}).(string)
+	return value, ok
+}
+
+// LocaleFromContext returns the locale of a request, if any
+func LocaleFromContext(ctx context.Context) (string, bool) {
+	value, ok := ctx.Value(localeKey{}).(string)
+	return value, ok
+}
@@ -0,0 +1,64 @@
+package wssecurity
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/xml"
+	"time"
+)
+
+// ClientHeader is a Security header for clients. Each time it is marshaled
+// it creates a new timestamp and, for digests, a new nonce, so one header
+// can be added to a gowsdl client for all of its requests:
+//
+//	client := soap.NewClient(url)
+//	client.AddHeader(&wssecurity.ClientHeader{Username: "alice", Password: "secret", Digest: true})
+type ClientHeader struct {
+	Username string
+	Password string
+	// Digest sends the password as a digest instead of in plain text
+	Digest bool
+	// TTL is how long the message is valid for; the default is one minute
+	TTL time.Duration
+}
+
+// MarshalXML implements xml.Marshaler
+func (h *ClientHeader) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	security, err := h.Security(time.Now())
+	if err != nil {
+		return err
+	}
+	return e.Encode(security)
+}
+
+// Security returns the header for a message created at the given time
+func (h *ClientHeader) Security(now time.Time) (*Security, error) {
+	ttl := h.TTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	created := now.UTC().Format(time.RFC3339Nano)
+
+	token := &UsernameToken{
+		Username: h.Username,
+		Password: &Password{Type: PasswordText, Value: h.Password},
+	}
+	if h.Digest {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		token.Nonce = &Nonce{EncodingType: Base64Binary, Value: base64.StdEncoding.EncodeToString(nonce)}
+		token.Created = created
+		token.Password = &Password{Type: PasswordDigest, Value: Digest(nonce, created, h.Password)}
+	}
+
+	return &Security{
+		MustUnderstand: "1",
+		Timestamp: &Timestamp{
+			Created: created,
+			Expires: now.Add(ttl).UTC().Format(time.RFC3339Nano),
+		},
+		UsernameToken: token,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package wssecurity
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Credentials looks up the password of a user. Password digests are
+// computed from the password itself, so it cannot be stored hashed.
+type Credentials interface {
+	Password(username string) (string, bool)
+}
+
+// Store is a fixed set of credentials by username
+type Store map[string]string
+
+// Password implements Credentials
+func (s Store) Password(username string) (string, bool) {
+	password, ok := s[username]
+	return password, ok
+}
+
+// LoadFile reads credentials from a file with one username:password pair
+// per line. Blank lines and lines starting with # are ignored.
+func LoadFile(path string) (Store, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	store := make(Store)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		username, password, ok := strings.Cut(text, ":")
+		if !ok || username == "" || password == "" {
+			return nil, fmt.Errorf("%s:%d: expected username:password", path, line)
+		}
+		if _, dup := store[username]; dup {
+			return nil, fmt.Errorf("%s:%d: duplicate user %s", path, line, username)
+		}
+		store[username] = password
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(store) == 0 {
+		return nil, fmt.Errorf("%s: no credentials", path)
+	}
+	return store, nil
+}
@@ -0,0 +1,12 @@
+module wssecurity
+
+go 1.21
+
+require (
+	soapfault v0.0.0
+	soapheader v0.0.0
+)
+
+replace soapfault => ../soapfault
+
+replace soapheader => ../soapheader
@@ -0,0 +1,69 @@
+// Package wssecurity authenticates SOAP requests with the WS-Security
+// UsernameToken profile. A Verifier checks the wsse:Security header of a
+// request: the username and password, sent in plain text or as a digest
+// with a nonce that may only be used once, and the freshness of the token
+// and of the wsu:Timestamp. Requests that fail are answered with
+// wsse:FailedAuthentication faults.
+//
+// HandleHeader reads the header for a soapheader.Registry, after which
+// Verifier.Authenticate checks it. ClientHeader adds the header to the
+// requests of a gowsdl client.
+package wssecurity
+
+import "encoding/xml"
+
+// Namespaces of the WS-Security elements
+const (
+	Namespace        = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	UtilityNamespace = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+)
+
+// Password types of the UsernameToken profile
+const (
+	PasswordText   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	PasswordDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+)
+
+// Base64Binary is the encoding type of nonces
+const Base64Binary = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+
+// Name is the qualified name of the Security header element
+var Name = xml.Name{Space: Namespace, Local: "Security"}
+
+// Security is the wsse:Security header
+type Security struct {
+	XMLName xml.Name `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Security"`
+	// MustUnderstand is the SOAP 1.1 mustUnderstand attribute, set by clients
+	MustUnderstand string         `xml:"http://schemas.xmlsoap.org/soap/envelope/ mustUnderstand,attr,omitempty"`
+	Timestamp      *Timestamp     `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Timestamp,omitempty"`
+	UsernameToken  *UsernameToken `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd UsernameToken,omitempty"`
+}
+
+// UsernameToken carries the credentials of the client
+type UsernameToken struct {
+	Username string    `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Username"`
+	Password *Password `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Password,omitempty"`
+	Nonce    *Nonce    `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd Nonce,omitempty"`
+	// Created is the xsd:dateTime the token was created at
+	Created string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created,omitempty"`
+}
+
+// Password is the password in plain text or its digest; Type is one of
+// PasswordText, the default, and PasswordDigest
+type Password struct {
+	Type  string `xml:"Type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+// Nonce is a random value that makes each digest unique
+type Nonce struct {
+	EncodingType string `xml:"EncodingType,attr,omitempty"`
+	Value        string `xml:",chardata"`
+}
+
+// Timestamp gives the time the message was created and, optionally, when
+// it expires, as xsd:dateTime values
+type Timestamp struct {
+	Created string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Created"`
+	Expires string `xml:"http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd Expires,omitempty"`
+}
@@ -0,0 +1,275 @@
+package wssecurity
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"soapfault"
+	"soapheader"
+)
+
+// Fault codes of the WS-Security specification
+var (
+	FailedAuthenticationCode = &soapfault.Subcode{Namespace: Namespace, Prefix: "wsse", Local: "FailedAuthentication"}
+	InvalidSecurityCode      = &soapfault.Subcode{Namespace: Namespace, Prefix: "wsse", Local: "InvalidSecurity"}
+)
+
+// Verifier authenticates requests by their Security header against a
+// credential store. It remembers the nonces of the tokens it accepted until
+// the tokens are too old to be accepted anyway, and rejects tokens that
+// reuse one of them.
+type Verifier struct {
+	credentials Credentials
+
+	// MaxAge limits how old the Created time of a token or timestamp
+	// without an Expires time may be
+	MaxAge time.Duration
+	// ClockSkew is the difference between the clocks of clients and the
+	// server that is tolerated when checking Created and Expires times
+	ClockSkew time.Duration
+	// RequireTimestamp rejects requests without a wsu:Timestamp
+	RequireTimestamp bool
+
+	mu sync.Mutex
+	// nonces holds the nonces in use by username, with the time they may be forgotten
+	nonces    map[nonceKey]time.Time
+	lastSweep time.Time
+}
+
+type nonceKey struct {
+	username string
+	// nonce is the decoded nonce
+	nonce string
+}
+
+// NewVerifier creates a verifier that accepts tokens up to five minutes old
+// with a minute of clock skew
+func NewVerifier(credentials Credentials) *Verifier {
+	return &Verifier{
+		credentials: credentials,
+		MaxAge:      5 * time.Minute,
+		ClockSkew:   time.Minute,
+		nonces:      make(map[nonceKey]time.Time),
+	}
+}
+
+// Verify checks the Security header of a request, nil if it has none, and
+// returns the authenticated username. Its errors are *soapfault.Error
+// values with the FailedAuthentication or, for headers that cannot be
+// read, the InvalidSecurity subcode.
+func (v *Verifier) Verify(header *Security) (string, error) {
+	if header == nil {
+		return "", failed("missing wsse:Security header")
+	}
+	now := time.Now()
+
+	if header.Timestamp == nil {
+		if v.RequireTimestamp {
+			return "", failed("missing wsu:Timestamp")
+		}
+	} else if err := v.checkTimestamp(header.Timestamp, now); err != nil {
+		return "", err
+	}
+
+	token := header.UsernameToken
+	if token == nil || token.Username == "" {
+		return "", failed("missing wsse:UsernameToken")
+	}
+	var created time.Time
+	if token.Created != "" {
+		var err error
+		if created, err = parseTime("wsse:UsernameToken/wsu:Created", token.Created); err != nil {
+			return "", err
+		}
+		if err := v.checkFresh("wsse:UsernameToken", created, time.Time{}, now); err != nil {
+			return "", err
+		}
+	}
+	var nonce []byte
+	if token.Nonce != nil {
+		if token.Nonce.EncodingType != "" && token.Nonce.EncodingType != Base64Binary {
+			return "", invalid("unsupported wsse:Nonce encoding %s", token.Nonce.EncodingType)
+		}
+		var err error
+		if nonce, err = base64.StdEncoding.DecodeString(token.Nonce.Value); err != nil || len(nonce) == 0 {
+			return "", invalid("wsse:Nonce is not base64 encoded")
+		}
+		// A nonce is only remembered as long as its token is fresh
+		if created.IsZero() {
+			return "", invalid("wsse:Nonce requires wsu:Created")
+		}
+	}
+
+	if err := v.checkPassword(token, nonce); err != nil {
+		return "", err
+	}
+	if nonce != nil {
+		if err := v.useNonce(token.Username, nonce, created, now); err != nil {
+			return "", err
+		}
+	}
+	return token.Username, nil
+}
+
+// checkTimestamp checks that a timestamp is neither from the future nor expired
+func (v *Verifier) checkTimestamp(timestamp *Timestamp, now time.Time) error {
+	created, err := parseTime("wsu:Timestamp/wsu:Created", timestamp.Created)
+	if err != nil {
+		return err
+	}
+	var expires time.Time
+	if timestamp.Expires != "" {
+		if expires, err = parseTime("wsu:Timestamp/wsu:Expires", timestamp.Expires); err != nil {
+			return err
+		}
+		if expires.Before(created) {
+			return invalid("wsu:Timestamp expires before it is created")
+		}
+	}
+	return v.checkFresh("wsu:Timestamp", created, expires, now)
+}
+
+// checkFresh checks a Created time and an optional Expires time against
+// the current time, allowing for clock skew
+func (v *Verifier) checkFresh(element string, created, expires, now time.Time) error {
+	if created.After(now.Add(v.ClockSkew)) {
+		return failed("%s is created in the future", element)
+	}
+	if expires.IsZero() {
+		expires = created.Add(v.MaxAge)
+	}
+	if now.After(expires.Add(v.ClockSkew)) {
+		return failed("%s has expired", element)
+	}
+	return nil
+}
+
+// checkPassword compares the password of a token with the stored one, in
+// plain text or as the digest Base64(SHA-1(nonce + created + password))
+func (v *Verifier) checkPassword(token *UsernameToken, nonce []byte) error {
+	if token.Password == nil {
+		return failed("missing wsse:Password")
+	}
+	// Unknown users are compared against an empty password, so they take
+	// as long to reject as wrong passwords
+	password, known := v.credentials.Password(token.Username)
+
+	var expected string
+	switch token.Password.Type {
+	case "", PasswordText:
+		expected = password
+	case PasswordDigest:
+		if nonce == nil {
+			return invalid("a password digest requires wsse:Nonce and wsu:Created")
+		}
+		expected = Digest(nonce, token.Created, password)
+	default:
+		return invalid("unsupported password type %s", token.Password.Type)
+	}
+
+	match := subtle.ConstantTimeCompare([]byte(token.Password.Value), []byte(expected)) == 1
+	if !known || !match {
+		return failed("invalid username or password")
+	}
+	return nil
+}
+
+// useNonce records the nonce of an accepted token, failing if it was used
+// before. Nonces are compared decoded, as the digest is: the base64 text of
+// the same nonce can be written in several ways, e.g. with line breaks.
+func (v *Verifier) useNonce(username string, nonce []byte, created, now time.Time) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if now.Sub(v.lastSweep) > v.MaxAge {
+		for key, forget := range v.nonces {
+			if now.After(forget) {
+				delete(v.nonces, key)
+			}
+		}
+		v.lastSweep = now
+	}
+
+	key := nonceKey{username: username, nonce: string(nonce)}
+	if forget, ok := v.nonces[key]; ok && !now.After(forget) {
+		return failed("wsse:Nonce has already been used")
+	}
+	// After this the token fails checkFresh, even with the clock skew
+	v.nonces[key] = created.Add(v.MaxAge + 2*v.ClockSkew)
+	return nil
+}
+
+// Digest computes the password digest of the UsernameToken profile
+func Digest(nonce []byte, created, password string) string {
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// parseTime parses an xsd:dateTime of a WS-Security element
+func parseTime(element, value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, invalid("%s is not a valid xsd:dateTime", element)
+	}
+	return t, nil
+}
+
+// failed returns a fault for a request that could not be authenticated
+func failed(format string, args ...interface{}) *soapfault.Error {
+	fault := soapfault.New(soapfault.Client, soapfault.FailedAuthentication,
+		"The security token could not be authenticated or authorized", fmt.Errorf(format, args...))
+	fault.Subcode = FailedAuthenticationCode
+	return fault
+}
+
+// invalid returns a fault for a Security header that cannot be processed
+func invalid(format string, args ...interface{}) *soapfault.Error {
+	fault := soapfault.New(soapfault.Client, soapfault.InvalidSecurity,
+		"An error was discovered processing the <wsse:Security> header", fmt.Errorf(format, args...))
+	fault.Subcode = InvalidSecurityCode
+	return fault
+}
+
+// HandleHeader is the soapheader.Handler of the Security header. It reads
+// the header into the context of the request for Authenticate.
+func HandleHeader(ctx context.Context, entry *soapheader.Entry) (context.Context, error) {
+	security := new(Security)
+	if err := entry.Decode(security); err != nil {
+		return ctx, invalid("%v", err)
+	}
+	return context.WithValue(ctx, headerKey{}, security), nil
+}
+
+// Authenticate verifies the Security header read by HandleHeader and
+// returns the context with the authenticated username
+func (v *Verifier) Authenticate(ctx context.Context) (context.Context, error) {
+	security, _ := ctx.Value(headerKey{}).(*Security)
+	username, err := v.Verify(security)
+	if err != nil {
+		return ctx, err
+	}
+	return NewContext(ctx, username), nil
+}
+
+type headerKey struct{}
+
+type contextKey struct{}
+
+// NewContext returns a context carrying the authenticated username
+func NewContext(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, contextKey{}, username)
+}
+
+// UsernameFromContext returns the authenticated username of a request, if any
+func UsernameFromContext(ctx context.Context) (string, bool) {
+	username, ok := ctx.Value(contextKey{}).(string)
+	return username, ok
+}
@@ -0,0 +1,7 @@
+module xmllimit
+
+go 1.21
+
+require soapfault v0.0.0
+
+replace soapfault => ../soapfault
@@ -0,0 +1,139 @@
+// Package xmllimit protects the SOAP endpoints against oversized and
+// malicious XML. Limits caps the size of request bodies, how deeply their
+// elements nest and how many attributes an element has, and rejects
+// document type declarations, which are where entities are declared.
+// Requests that break a limit fail with a *soapfault.Error, which stops the
+// decoding as soon as the limit is reached:
+//
+//	decoder := limits.NewDecoder(xml.NewDecoder(limits.Body(w, r)))
+//
+// encoding/xml itself expands only the predefined and character entities
+// and never reads external resources.
+package xmllimit
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"soapfault"
+)
+
+// Limits are the limits requests must stay within; a zero limit is no limit
+type Limits struct {
+	// MaxBodySize is the size of the request body in bytes
+	MaxBodySize int64
+	// MaxDepth is how deeply elements may nest, the Envelope being at depth 1
+	MaxDepth int
+	// MaxAttributes is the number of attributes of an element, including
+	// namespace declarations
+	MaxAttributes int
+}
+
+// Default are the limits of the services unless they are configured otherwise
+var Default = Limits{
+	MaxBodySize:   4 << 20,
+	MaxDepth:      32,
+	MaxAttributes: 32,
+}
+
+// Body returns the body of a request, limited to MaxBodySize with
+// http.MaxBytesReader. A body whose Content-Length is over the limit fails
+// before it is read, any other once the limit is reached, both with a
+// REQUEST_TOO_LARGE fault; the server then closes the connection rather than
+// read the rest.
+func (l Limits) Body(w http.ResponseWriter, r *http.Request) io.Reader {
+	if l.MaxBodySize <= 0 {
+		return r.Body
+	}
+	if r.ContentLength > l.MaxBodySize {
+		return &bodyReader{err: tooLarge(l.MaxBodySize)}
+	}
+	return &bodyReader{body: http.MaxBytesReader(w, r.Body, l.MaxBodySize), limit: l.MaxBodySize}
+}
+
+// bodyReader turns the error of http.MaxBytesReader into a fault
+type bodyReader struct {
+	body  io.Reader
+	limit int64
+	// err fails every read when set
+	err error
+}
+
+func (r *bodyReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.body.Read(p)
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		r.err = tooLarge(r.limit)
+		return n, r.err
+	}
+	return n, err
+}
+
+// Decoder reads the tokens of an *xml.Decoder within the depth and attribute
+// limits and rejects document type declarations. It is an xml.TokenReader,
+// so xml.NewTokenDecoder can unmarshal values from it, and unlike such a
+// decoder it reports the position in the input.
+type Decoder struct {
+	decoder *xml.Decoder
+	limits  Limits
+	depth   int
+	err     error
+}
+
+// NewDecoder returns a decoder of the tokens of d within the limits. Options
+// such as the CharsetReader must be set on d.
+func (l Limits) NewDecoder(d *xml.Decoder) *Decoder {
+	return &Decoder{decoder: d, limits: l}
+}
+
+// Token returns the next token, as xml.Decoder.Token does, or a fault once
+// the document exceeds a limit
+func (d *Decoder) Token() (xml.Token, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	token, err := d.decoder.Token()
+	if err != nil {
+		return token, err
+	}
+	switch t := token.(type) {
+	case xml.StartElement:
+		d.depth++
+		if max := d.limits.MaxDepth; max > 0 && d.depth > max {
+			d.err = exceeded(fmt.Errorf("element %s is nested more than %d levels deep", t.Name.Local, max))
+		} else if max := d.limits.MaxAttributes; max > 0 && len(t.Attr) > max {
+			d.err = exceeded(fmt.Errorf("element %s has %d attributes, at most %d are allowed", t.Name.Local, len(t.Attr), max))
+		}
+	case xml.EndElement:
+		d.depth--
+	case xml.Directive:
+		d.err = soapfault.New(soapfault.Client, soapfault.DTDNotAllowed, "Document type declarations are not allowed",
+			errors.New("the request contains a <!DOCTYPE> or other declaration"))
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return token, nil
+}
+
+// InputPos returns the line and column in the input after the last token
+func (d *Decoder) InputPos() (line, column int) {
+	return d.decoder.InputPos()
+}
+
+// tooLarge returns the fault for a body over the size limit
+func tooLarge(limit int64) *soapfault.Error {
+	return soapfault.New(soapfault.Client, soapfault.RequestTooLarge, "Request too large",
+		fmt.Errorf("the request body exceeds %d bytes", limit))
+}
+
+// exceeded returns the fault for a request over the depth or attribute limit
+func exceeded(err error) *soapfault.Error {
+	return soapfault.New(soapfault.Client, soapfault.XMLLimitExceeded, "Request exceeds XML limits", err)
+}